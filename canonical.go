@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+// canonicalHost returns a copy of the host with its endpoints sorted by IP address and
+// their protocols sorted by id, so the same result always encodes to the same JSON
+// regardless of the order the API listed them in
+func canonicalHost(host *ssllabs.Host) *ssllabs.Host {
+	canonical := *host
+	canonical.Endpoints = append([]ssllabs.Endpoint(nil), host.Endpoints...)
+	sort.SliceStable(canonical.Endpoints, func(i, j int) bool {
+		return canonical.Endpoints[i].IpAddress < canonical.Endpoints[j].IpAddress
+	})
+	for i := range canonical.Endpoints {
+		protocols := append([]ssllabs.Protocol(nil), canonical.Endpoints[i].Details.Protocols...)
+		sort.SliceStable(protocols, func(a, b int) bool {
+			return protocols[a].ID < protocols[b].ID
+		})
+		canonical.Endpoints[i].Details.Protocols = protocols
+	}
+	return &canonical
+}
+
+// contentHash returns the SHA-256 hash of the canonical JSON encoding of a result document
+// with an empty contentHash field, so identical results can be recognized without comparing
+// them field by field. The hash covers the host, the notes, the mixed content and the
+// direct probe: a result whose notes changed is a different result.
+func contentHash(result *ResultDocument) string {
+	unhashed := *result
+	unhashed.Host = canonicalHost(result.Host)
+	unhashed.ContentHash = ""
+	var buf bytes.Buffer
+	// Encoding a result only fails on invalid raw JSON, which the host never carries
+	writeJSON(&buf, &unhashed)
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+func TestContentHash(t *testing.T) {
+	host := &ssllabs.Host{Host: "example.com", Status: "READY", Endpoints: []ssllabs.Endpoint{{IpAddress: "192.0.2.2"}, {IpAddress: "192.0.2.1"}}}
+	reordered := &ssllabs.Host{Host: "example.com", Status: "READY", Endpoints: []ssllabs.Endpoint{{IpAddress: "192.0.2.1"}, {IpAddress: "192.0.2.2"}}}
+	result := newResultDocument(host, []string{"pending LB migration"}, nil)
+	if hash := newResultDocument(reordered, []string{"pending LB migration"}, nil).ContentHash; hash != result.ContentHash {
+		t.Errorf("reordered endpoints: hash %s, want %s", hash, result.ContentHash)
+	}
+	if hash := newResultDocument(host, []string{"fixed"}, nil).ContentHash; hash == result.ContentHash {
+		t.Error("changed notes: want a different hash")
+	}
+	if hash := newResultDocument(host, []string{"pending LB migration"}, []string{"http://example.com/a.js"}).ContentHash; hash == result.ContentHash {
+		t.Error("mixed content: want a different hash")
+	}
+	// The hash is reproducible from the written document
+	if hash := contentHash(result); hash != result.ContentHash {
+		t.Errorf("recomputed hash %s, want %s", hash, result.ContentHash)
+	}
+}
+
+func TestWriteJSONSortsKeys(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, struct {
+		Zone  string `json:"zone"`
+		Alpha int64  `json:"alpha"`
+	}{"z", 1 << 60}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Index(out, `"alpha"`) > strings.Index(out, `"zone"`) {
+		t.Errorf("writeJSON = %s, want sorted keys", out)
+	}
+	// Large integers keep their precision
+	if !strings.Contains(out, "1152921504606846976") {
+		t.Errorf("writeJSON = %s, want the exact integer", out)
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// TLSA certificate usages (RFC 6698)
+const (
+	tlsaUsagePKIXTA = 0
+	tlsaUsagePKIXEE = 1
+	tlsaUsageDANETA = 2
+	tlsaUsageDANEEE = 3
+)
+
+// TLSA selectors and matching types (RFC 6698)
+const (
+	tlsaSelectorCert = 0
+	tlsaSelectorSPKI = 1
+	tlsaMatchFull    = 0
+	tlsaMatchSHA256  = 1
+	tlsaMatchSHA512  = 2
+)
+
+// DNS resource record type of TLSA records, which dnsmessage does not define
+const dnsTypeTLSA dnsmessage.Type = 52
+
+// Structs to describe a TLSA record
+type tlsaRecord struct {
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	Data         []byte
+}
+
+// Structs to describe the TLSA records of a service. Secure is set when the resolver
+// validated them with DNSSEC, only then may they be used for DANE.
+type tlsaLookup struct {
+	Records []tlsaRecord
+	Secure  bool
+}
+
+// resolvConfPath is the resolver configuration the DNS server is read from
+const resolvConfPath = "/etc/resolv.conf"
+
+// systemDNSServer returns the address of the first name server of the system resolver
+func systemDNSServer() (string, error) {
+	f, err := os.Open(resolvConfPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read resolver configuration: %v", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53"), nil
+		}
+	}
+	return "", fmt.Errorf("no name server configured in %s", resolvConfPath)
+}
+
+// lookupTLSA queries the TLSA records of a TCP service through the system resolver. The
+// records are only trusted when the resolver sets the authenticated data bit, so the
+// resolver must validate DNSSEC.
+func lookupTLSA(host string, port int, timeout time.Duration) (*tlsaLookup, error) {
+	server, err := systemDNSServer()
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("_%d._tcp.%s.", port, strings.TrimSuffix(host, "."))
+	id := make([]byte, 2)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate query ID: %v", err)
+	}
+	query, err := buildTLSAQuery(name, binary.BigEndian.Uint16(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build TLSA query: %v", err)
+	}
+	response, err := exchangeDNS(server, "udp", query, timeout)
+	if err == nil && truncated(response) {
+		// Large record sets do not fit in a datagram, retry over TCP
+		response, err = exchangeDNS(server, "tcp", query, timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("TLSA lookup of %s failed: %v", name, err)
+	}
+	lookup, err := parseTLSAResponse(response, binary.BigEndian.Uint16(id))
+	if err != nil {
+		return nil, fmt.Errorf("TLSA lookup of %s failed: %v", name, err)
+	}
+	return lookup, nil
+}
+
+// buildTLSAQuery builds a recursive TLSA query asking for DNSSEC validation
+func buildTLSAQuery(name string, id uint16) ([]byte, error) {
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true, AuthenticData: true})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: qname, Type: dnsTypeTLSA, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	if err := b.StartAdditionals(); err != nil {
+		return nil, err
+	}
+	// The DO bit asks the resolver to validate and report the outcome in the AD bit
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(4096, dnsmessage.RCodeSuccess, true); err != nil {
+		return nil, err
+	}
+	if err := b.OPTResource(opt, dnsmessage.OPTResource{}); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+// exchangeDNS sends a DNS query over UDP or TCP and returns the response
+func exchangeDNS(server, network string, query []byte, timeout time.Duration) ([]byte, error) {
+	conn, err := net.DialTimeout(network, server, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		response := make([]byte, 4096)
+		n, err := conn.Read(response)
+		if err != nil {
+			return nil, err
+		}
+		return response[:n], nil
+	}
+	// Messages over TCP are prefixed with their length
+	if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(query))), query...)); err != nil {
+		return nil, err
+	}
+	length := make([]byte, 2)
+	if _, err := io.ReadFull(conn, length); err != nil {
+		return nil, err
+	}
+	response := make([]byte, binary.BigEndian.Uint16(length))
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// truncated reports whether a DNS response has the truncation bit set
+func truncated(response []byte) bool {
+	var p dnsmessage.Parser
+	header, err := p.Start(response)
+	return err == nil && header.Truncated
+}
+
+// parseTLSAResponse extracts the TLSA records of a DNS response. A name without records
+// is not an error, it returns no records.
+func parseTLSAResponse(response []byte, id uint16) (*tlsaLookup, error) {
+	var p dnsmessage.Parser
+	header, err := p.Start(response)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+	if header.ID != id || !header.Response {
+		return nil, errors.New("response does not match the query")
+	}
+	lookup := &tlsaLookup{Secure: header.AuthenticData}
+	switch header.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return lookup, nil
+	default:
+		return nil, fmt.Errorf("server answered %s", header.RCode)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+	for {
+		answer, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid response: %v", err)
+		}
+		// CNAME records leading to the TLSA records are skipped
+		if answer.Type != dnsTypeTLSA {
+			if err := p.SkipAnswer(); err != nil {
+				return nil, fmt.Errorf("invalid response: %v", err)
+			}
+			continue
+		}
+		resource, err := p.UnknownResource()
+		if err != nil {
+			return nil, fmt.Errorf("invalid response: %v", err)
+		}
+		if len(resource.Data) < 3 {
+			return nil, errors.New("invalid TLSA record")
+		}
+		lookup.Records = append(lookup.Records, tlsaRecord{
+			Usage:        resource.Data[0],
+			Selector:     resource.Data[1],
+			MatchingType: resource.Data[2],
+			Data:         resource.Data[3:],
+		})
+	}
+	return lookup, nil
+}
+
+// matches reports whether a certificate matches the record
+func (r tlsaRecord) matches(cert *x509.Certificate) bool {
+	var data []byte
+	switch r.Selector {
+	case tlsaSelectorCert:
+		data = cert.Raw
+	case tlsaSelectorSPKI:
+		data = cert.RawSubjectPublicKeyInfo
+	default:
+		return false
+	}
+	switch r.MatchingType {
+	case tlsaMatchFull:
+	case tlsaMatchSHA256:
+		sum := sha256.Sum256(data)
+		data = sum[:]
+	case tlsaMatchSHA512:
+		sum := sha512.Sum512(data)
+		data = sum[:]
+	default:
+		return false
+	}
+	return bytes.Equal(data, r.Data)
+}
+
+// daneVerify checks the certificates presented by an SMTP server against its TLSA records
+// following RFC 7672: DANE-EE records match the leaf certificate regardless of its names
+// and validity, DANE-TA records match a certificate of the chain that must then issue a
+// leaf valid for the host, and PKIX usages are unusable for SMTP. It returns the number of
+// usable records and whether one of them matched.
+func daneVerify(records []tlsaRecord, host string, certs []*x509.Certificate, now time.Time) (usable int, matched bool) {
+	if len(certs) == 0 {
+		return 0, false
+	}
+	for _, record := range records {
+		switch record.Usage {
+		case tlsaUsageDANEEE:
+			usable++
+			matched = matched || record.matches(certs[0])
+		case tlsaUsageDANETA:
+			usable++
+			for _, cert := range certs[1:] {
+				if record.matches(cert) && issuedBy(certs, cert, host, now) {
+					matched = true
+				}
+			}
+		}
+	}
+	return usable, matched
+}
+
+// issuedBy reports whether the leaf of a chain verifies for the host with the given
+// trust anchor
+func issuedBy(certs []*x509.Certificate, anchor *x509.Certificate, host string, now time.Time) bool {
+	roots := x509.NewCertPool()
+	roots.AddCert(anchor)
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{DNSName: host, Roots: roots, Intermediates: intermediates, CurrentTime: now})
+	return err == nil
+}
+
+// tlsaUsageName returns a display name for a TLSA certificate usage
+func tlsaUsageName(usage uint8) string {
+	switch usage {
+	case tlsaUsagePKIXTA:
+		return "PKIX-TA"
+	case tlsaUsagePKIXEE:
+		return "PKIX-EE"
+	case tlsaUsageDANETA:
+		return "DANE-TA"
+	case tlsaUsageDANEEE:
+		return "DANE-EE"
+	default:
+		return "usage " + strconv.Itoa(int(usage))
+	}
+}
+
+// tlsaUsages returns the display names of the certificate usages of a set of records
+func tlsaUsages(records []tlsaRecord) []string {
+	var usages []string
+	for _, record := range records {
+		usages = append(usages, tlsaUsageName(record.Usage))
+	}
+	return usages
+}
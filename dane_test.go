@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// tlsaResponse builds the response to a TLSA query holding the given records
+func tlsaResponse(t *testing.T, id uint16, authenticated bool, rcode dnsmessage.RCode, records ...tlsaRecord) []byte {
+	t.Helper()
+	name := dnsmessage.MustNewName("_25._tcp.mx.example.com.")
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, Response: true, AuthenticData: authenticated, RCode: rcode})
+	if err := b.StartQuestions(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Question(dnsmessage.Question{Name: name, Type: dnsTypeTLSA, Class: dnsmessage.ClassINET}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.StartAnswers(); err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		data := append([]byte{record.Usage, record.Selector, record.MatchingType}, record.Data...)
+		header := dnsmessage.ResourceHeader{Name: name, Type: dnsTypeTLSA, Class: dnsmessage.ClassINET, TTL: 300}
+		if err := b.UnknownResource(header, dnsmessage.UnknownResource{Type: dnsTypeTLSA, Data: data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	response, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestBuildTLSAQuery(t *testing.T) {
+	query, err := buildTLSAQuery("_25._tcp.mx.example.com.", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p dnsmessage.Parser
+	header, err := p.Start(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.ID != 42 || !header.RecursionDesired || !header.AuthenticData {
+		t.Errorf("header = %+v, want ID 42 with RD and AD set", header)
+	}
+	question, err := p.Question()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if question.Type != dnsTypeTLSA || question.Name.String() != "_25._tcp.mx.example.com." {
+		t.Errorf("question = %v", question)
+	}
+}
+
+func TestParseTLSAResponse(t *testing.T) {
+	record := tlsaRecord{Usage: tlsaUsageDANEEE, Selector: tlsaSelectorSPKI, MatchingType: tlsaMatchSHA256, Data: make([]byte, 32)}
+	lookup, err := parseTLSAResponse(tlsaResponse(t, 7, true, dnsmessage.RCodeSuccess, record), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lookup.Secure || len(lookup.Records) != 1 || lookup.Records[0].Usage != tlsaUsageDANEEE || len(lookup.Records[0].Data) != 32 {
+		t.Errorf("lookup = %+v, want one secure DANE-EE record", lookup)
+	}
+	lookup, err = parseTLSAResponse(tlsaResponse(t, 7, false, dnsmessage.RCodeNameError), 7)
+	if err != nil || lookup.Secure || len(lookup.Records) != 0 {
+		t.Errorf("NXDOMAIN: lookup = %+v, err = %v, want no records", lookup, err)
+	}
+	if _, err := parseTLSAResponse(tlsaResponse(t, 7, false, dnsmessage.RCodeServerFailure), 7); err == nil {
+		t.Error("SERVFAIL: want an error")
+	}
+	if _, err := parseTLSAResponse(tlsaResponse(t, 8, true, dnsmessage.RCodeSuccess, record), 7); err == nil {
+		t.Error("mismatched ID: want an error")
+	}
+}
+
+func TestDANEVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	// The test certificate is a self-signed CA valid for example.com
+	cert := server.Certificate()
+	spki := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	other := make([]byte, 32)
+	now := time.Now()
+	tests := []struct {
+		name        string
+		records     []tlsaRecord
+		chain       []*x509.Certificate
+		host        string
+		wantUsable  int
+		wantMatched bool
+	}{
+		{"DANE-EE SPKI SHA-256", []tlsaRecord{{tlsaUsageDANEEE, tlsaSelectorSPKI, tlsaMatchSHA256, spki[:]}}, nil, "example.com", 1, true},
+		{"DANE-EE full certificate", []tlsaRecord{{tlsaUsageDANEEE, tlsaSelectorCert, tlsaMatchFull, cert.Raw}}, nil, "example.com", 1, true},
+		{"DANE-EE ignores the host name", []tlsaRecord{{tlsaUsageDANEEE, tlsaSelectorSPKI, tlsaMatchSHA256, spki[:]}}, nil, "mx.example.net", 1, true},
+		{"DANE-TA", []tlsaRecord{{tlsaUsageDANETA, tlsaSelectorSPKI, tlsaMatchSHA256, spki[:]}}, []*x509.Certificate{cert, cert}, "example.com", 1, true},
+		{"DANE-TA checks the host name", []tlsaRecord{{tlsaUsageDANETA, tlsaSelectorSPKI, tlsaMatchSHA256, spki[:]}}, []*x509.Certificate{cert, cert}, "mx.example.net", 1, false},
+		{"DANE-EE mismatch", []tlsaRecord{{tlsaUsageDANEEE, tlsaSelectorSPKI, tlsaMatchSHA256, other}}, nil, "example.com", 1, false},
+		{"one of several", []tlsaRecord{{tlsaUsageDANEEE, tlsaSelectorSPKI, tlsaMatchSHA256, other}, {tlsaUsageDANEEE, tlsaSelectorSPKI, tlsaMatchSHA256, spki[:]}}, nil, "example.com", 2, true},
+		{"PKIX usages are unusable", []tlsaRecord{{tlsaUsagePKIXEE, tlsaSelectorSPKI, tlsaMatchSHA256, spki[:]}, {tlsaUsagePKIXTA, tlsaSelectorSPKI, tlsaMatchSHA256, spki[:]}}, nil, "example.com", 0, false},
+	}
+	for _, tt := range tests {
+		chain := tt.chain
+		if chain == nil {
+			chain = []*x509.Certificate{cert}
+		}
+		usable, matched := daneVerify(tt.records, tt.host, chain, now)
+		if usable != tt.wantUsable || matched != tt.wantMatched {
+			t.Errorf("%s: daneVerify = %d, %t, want %d, %t", tt.name, usable, matched, tt.wantUsable, tt.wantMatched)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// PostgreSQL SSLRequest code, sent in place of a protocol version
+const postgresSSLRequestCode = 80877103
+
+// negotiatePostgres sends a PostgreSQL SSLRequest and checks the server accepts it
+func negotiatePostgres(conn net.Conn) error {
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], postgresSSLRequestCode)
+	if _, err := conn.Write(request); err != nil {
+		return fmt.Errorf("failed to send SSLRequest: %v", err)
+	}
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("failed to read SSLRequest reply: %v", err)
+	}
+	switch reply[0] {
+	case 'S':
+		return nil
+	case 'N':
+		return fmt.Errorf("server does not support SSL")
+	default:
+		return fmt.Errorf("unexpected SSLRequest reply %q", reply[0])
+	}
+}
+
+// MySQL capability flags used to request TLS
+const (
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientSSL              = 0x00000800
+	mysqlClientSecureConnection = 0x00008000
+)
+
+// negotiateMySQL reads the MySQL initial handshake and answers with an SSL request packet
+func negotiateMySQL(conn net.Conn) error {
+	seq, payload, err := readMySQLPacket(conn)
+	if err != nil {
+		return fmt.Errorf("failed to read handshake: %v", err)
+	}
+	// An error packet is sent instead of the handshake, e.g. when the host is blocked
+	if len(payload) > 3 && payload[0] == 0xff {
+		return fmt.Errorf("server error: %s", payload[3:])
+	}
+	if len(payload) == 0 || payload[0] != 10 {
+		return fmt.Errorf("unsupported handshake protocol version")
+	}
+	// Skip the server version, connection id, first auth data part and filler
+	end := 1
+	for end < len(payload) && payload[end] != 0 {
+		end++
+	}
+	offset := end + 1 + 4 + 8 + 1
+	if len(payload) < offset+2 {
+		return fmt.Errorf("truncated handshake")
+	}
+	if binary.LittleEndian.Uint16(payload[offset:])&mysqlClientSSL == 0 {
+		return fmt.Errorf("server does not support SSL")
+	}
+	// Capability flags, max packet size, character set and 23 bytes of filler
+	request := make([]byte, 32)
+	binary.LittleEndian.PutUint32(request[0:4], mysqlClientProtocol41|mysqlClientSSL|mysqlClientSecureConnection)
+	binary.LittleEndian.PutUint32(request[4:8], 1<<24)
+	request[8] = 0x21
+	return writeMySQLPacket(conn, seq+1, request)
+}
+
+// readMySQLPacket reads a single MySQL protocol packet and returns its sequence id and payload
+func readMySQLPacket(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[3], payload, nil
+}
+
+// writeMySQLPacket writes a single MySQL protocol packet
+func writeMySQLPacket(w io.Writer, seq byte, payload []byte) error {
+	packet := make([]byte, 4, 4+len(payload))
+	packet[0] = byte(len(payload))
+	packet[1] = byte(len(payload) >> 8)
+	packet[2] = byte(len(payload) >> 16)
+	packet[3] = seq
+	if _, err := w.Write(append(packet, payload...)); err != nil {
+		return fmt.Errorf("failed to send SSL request: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+// Exit code used by collect when the assessment is still in progress
+const exitNotReady = 3
+
+// Structs to describe the handle printed by start and read by collect
+type assessmentHandle struct {
+	Host      string `json:"host"`
+	StartTime int64  `json:"startTime"`
+}
+
+// runStart implements the start subcommand: it submits a new assessment and prints its
+// handle on stdout without waiting for the results. It returns the process exit code.
+func runStart(args []string) int {
+	fs := flag.NewFlagSet("start", flag.ExitOnError)
+	publish := fs.Bool("publish", false, "Publish results on SSL Labs board")
+	assumeYes := fs.Bool("yes", false, "Publish without asking for confirmation")
+	verifyToken := fs.String("verify-ownership", "", "With -publish, refuse to publish unless the domain publishes this token")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ssl-checker start [-publish [-yes] [-verify-ownership token]] example.com > handle.json")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	// Make sure publishing is intended before anything is submitted
+	if *publish {
+		if err := confirmPublish([]string{fs.Arg(0)}, *assumeYes); err != nil {
+			logf("Error: %v\n", err)
+			return 1
+		}
+	}
+	// Ctrl-C cancels the context, interrupting the wait for a free assessment slot
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	// Refuse to publish results for domains whose ownership is not verified
+	if *publish && *verifyToken != "" {
+		if err := verifyOwnership(ctx, fs.Arg(0), *verifyToken, 10*time.Second); err != nil {
+			logf("Error: %v\n", err)
+			return 1
+		}
+	}
+	sslClient := ssllabs.NewClient()
+	// Wait for a free assessment slot and the cool-off period
+	if err := sslClient.WaitForCapacity(ctx); err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	host, err := sslClient.StartAssessment(ctx, fs.Arg(0), *publish)
+	if err != nil {
+		logf("Error starting assessment: %v\n", err)
+		return 1
+	}
+	logf("Assessment started for %s\n", host.Host)
+	// StartTime is only known once SSL Labs has accepted the assessment
+	startTime := host.StartTime
+	if startTime == 0 {
+		startTime = time.Now().UnixMilli()
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(assessmentHandle{Host: fs.Arg(0), StartTime: startTime}); err != nil {
+		logf("Error: failed to write handle: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runCollect implements the collect subcommand: it fetches the results of an assessment
+// submitted with start, exiting with code 3 if it is still in progress unless -wait is
+// given. It returns the process exit code.
+func runCollect(args []string) int {
+	fs := flag.NewFlagSet("collect", flag.ExitOnError)
+	wait := fs.Bool("wait", false, "Wait for the assessment to complete instead of exiting with code 3")
+	output := fs.String("output", outputText, "Results format: text, json, zabbix (zabbix_sender JSON) or checkmk (local check)")
+	resultsFile := fs.String("results-file", "", "Write the results to this file instead of stdout")
+	minGrade := fs.String("min-grade", "", "Exit with code 2 if any endpoint is graded below this grade (e.g., A)")
+	gradeModifiers := fs.String("grade-modifiers", gradeModifiersStrict, "How -min-grade treats +/- modifiers: strict (A- is below A) or ignore (A+, A and A- are equal)")
+	bestEffort := fs.String("best-effort", "", "Comma-separated IPs or CIDR ranges of endpoints to report but ignore in policy evaluation (e.g., CDN nodes)")
+	fs.Var(&scanNotes, "note", "Attach a free-text note to the scan, shown in the report (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ssl-checker collect [flags] handle.json")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	scanPolicy, err := newPolicy(*minGrade, *gradeModifiers, *bestEffort)
+	if err == nil {
+		err = validateOutput(*output)
+	}
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	// Read the handle written by start
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		logf("Error: failed to read handle: %v\n", err)
+		return 1
+	}
+	var handle assessmentHandle
+	if err := json.Unmarshal(data, &handle); err != nil || handle.Host == "" {
+		logf("Error: invalid handle %s\n", fs.Arg(0))
+		return 1
+	}
+	ctx := context.Background()
+	sslClient := ssllabs.NewClient()
+	host, err := sslClient.CheckAssessmentStatus(ctx, handle.Host)
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	if host.Status != "READY" && host.Status != "ERROR" {
+		if !*wait {
+			logf("Assessment for %s is still in progress (%s)\n", handle.Host, host.Status)
+			return exitNotReady
+		}
+		logln("Waiting for assessment to complete...")
+		host, err = sslClient.WaitForAssessment(ctx, handle.Host)
+		if err != nil {
+			logf("Error waiting for assessment: %v\n", err)
+			return 1
+		}
+	}
+	// A result older than the handle belongs to a previous assessment
+	if host.StartTime != 0 && host.StartTime < handle.StartTime {
+		logf("Warning: result for %s predates the submitted assessment\n", handle.Host)
+	}
+	result := newResultDocument(host, scanNotes, nil)
+	if err := writeResults(*resultsFile, result, *output, scanPolicy); err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	if violations := scanPolicy.violations(result, time.Now()); len(violations) > 0 {
+		for _, violation := range violations {
+			logf("Policy violation: %s\n", violation)
+		}
+		return exitPolicyViolation
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+// Structs to describe a pre-expiry checkpoint and the webhook notified once it is reached
+type expiryCheckpoint struct {
+	days int
+	url  string
+}
+
+// Structs to collect repeated -expiry-alert flags, sorted from the most urgent checkpoint
+type expiryLadder []expiryCheckpoint
+
+// String returns the checkpoints in DAYS=URL form
+func (l *expiryLadder) String() string {
+	var entries []string
+	for _, checkpoint := range *l {
+		entries = append(entries, fmt.Sprintf("%d=%s", checkpoint.days, checkpoint.url))
+	}
+	return strings.Join(entries, ",")
+}
+
+// Set adds a DAYS=URL checkpoint
+func (l *expiryLadder) Set(value string) error {
+	daysText, url, ok := strings.Cut(value, "=")
+	days, err := strconv.Atoi(daysText)
+	if !ok || err != nil || days < 1 || url == "" {
+		return fmt.Errorf("invalid expiry alert %q: expected DAYS=URL, e.g. 30=https://hooks.example.com/team", value)
+	}
+	*l = append(*l, expiryCheckpoint{days: days, url: url})
+	sort.SliceStable(*l, func(i, j int) bool {
+		return (*l)[i].days < (*l)[j].days
+	})
+	return nil
+}
+
+// checkpoint returns the most urgent checkpoint reached by a certificate expiring in the
+// given number of days, expired certificates reaching the most urgent one
+func (l expiryLadder) checkpoint(left int) (expiryCheckpoint, bool) {
+	for _, checkpoint := range l {
+		if left <= checkpoint.days {
+			return checkpoint, true
+		}
+	}
+	return expiryCheckpoint{}, false
+}
+
+// Structs to describe the data of a certificate expiry alert
+type ExpiryAlert struct {
+	Host       string   `json:"host"`
+	Endpoints  []string `json:"endpoints"`
+	Subject    string   `json:"subject"`
+	NotAfter   string   `json:"notAfter"`
+	DaysLeft   int      `json:"daysLeft"`
+	Checkpoint int      `json:"checkpoint"`
+}
+
+// Structs to describe an expiry alert and the webhook it is sent to
+type expiryNotification struct {
+	url   string
+	alert ExpiryAlert
+}
+
+// expiryNotifications groups the endpoints of the host by certificate and returns an alert
+// for every certificate that reached a checkpoint of the ladder
+func (l expiryLadder) expiryNotifications(host *ssllabs.Host, now time.Time) []expiryNotification {
+	var notifications []expiryNotification
+	seen := make(map[string]int)
+	for _, endpoint := range host.Endpoints {
+		cert := endpoint.Details.Cert
+		if cert.NotAfter == 0 {
+			continue
+		}
+		// The same certificate served by several endpoints is reported once
+		if i, ok := seen[certIdentity(cert)]; ok {
+			notifications[i].alert.Endpoints = append(notifications[i].alert.Endpoints, endpoint.IpAddress)
+			continue
+		}
+		left := daysLeft(certNotAfter(cert), now)
+		checkpoint, ok := l.checkpoint(left)
+		if !ok {
+			continue
+		}
+		seen[certIdentity(cert)] = len(notifications)
+		notifications = append(notifications, expiryNotification{url: checkpoint.url, alert: ExpiryAlert{
+			Host:       host.Host,
+			Endpoints:  []string{endpoint.IpAddress},
+			Subject:    cert.Subject,
+			NotAfter:   certNotAfter(cert).Format(time.RFC3339),
+			DaysLeft:   left,
+			Checkpoint: checkpoint.days,
+		}})
+	}
+	return notifications
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// writeEvidenceBundle writes a zip archive with the raw API response, the rendered
+// report, the certificates in PEM format and the scan manifest
+func writeEvidenceBundle(path string, result *ResultDocument, started, finished time.Time) error {
+	host := result.Host
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	// Add the raw API response
+	if err := addZipFile(zw, "api-response.json", host.Raw); err != nil {
+		return err
+	}
+	// Add the rendered report
+	var report bytes.Buffer
+	displayResults(&report, result)
+	if err := addZipFile(zw, "report.txt", report.Bytes()); err != nil {
+		return err
+	}
+	// Add the certificate chain of each endpoint
+	for _, endpoint := range host.Endpoints {
+		var pem strings.Builder
+		for _, cert := range endpoint.Details.Chain.Certs {
+			pem.WriteString(cert.Raw)
+		}
+		if pem.Len() == 0 {
+			continue
+		}
+		name := fmt.Sprintf("certs/%s.pem", strings.ReplaceAll(endpoint.IpAddress, ":", "_"))
+		if err := addZipFile(zw, name, []byte(pem.String())); err != nil {
+			return err
+		}
+	}
+	// Add the scan manifest
+	data, err := json.MarshalIndent(buildManifest(result, started, finished), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode evidence manifest: %v", err)
+	}
+	if err := addZipFile(zw, "manifest.json", data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize evidence bundle: %v", err)
+	}
+	// Write the archive to disk
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write evidence bundle: %v", err)
+	}
+	return nil
+}
+
+// addZipFile adds a single file with the given contents to a zip archive
+func addZipFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to evidence bundle: %v", name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to evidence bundle: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+// All expiry math goes through the helpers below. They work on absolute durations between
+// instants, so the report time zone, DST changes and leap seconds (which Unix time does not
+// count) never shift a result, and days are always whole 24-hour periods rounded down.
+
+// expiryDay is the unit of every expiry threshold
+const expiryDay = 24 * time.Hour
+
+// days converts a number of days to a duration
+func days(n int) time.Duration {
+	return time.Duration(n) * expiryDay
+}
+
+// certNotAfter returns the expiry time of a certificate reported by SSL Labs, in UTC
+func certNotAfter(cert ssllabs.Cert) time.Time {
+	return time.UnixMilli(cert.NotAfter).UTC()
+}
+
+// expired reports whether notAfter has been reached at the given time
+func expired(notAfter, now time.Time) bool {
+	return !notAfter.After(now)
+}
+
+// expiresWithin reports whether notAfter is less than window away, or already reached
+func expiresWithin(notAfter, now time.Time, window time.Duration) bool {
+	return notAfter.Sub(now) < window
+}
+
+// daysLeft returns the whole days left before notAfter, rounded down: a certificate
+// expiring in 23 hours has 0 days left and one that expired an hour ago has -1
+func daysLeft(notAfter, now time.Time) int {
+	left := notAfter.Sub(now)
+	n := int(left / expiryDay)
+	if left < 0 && left%expiryDay != 0 {
+		n--
+	}
+	return n
+}
+
+// certExpired reports whether a certificate has expired at the given time
+func certExpired(cert ssllabs.Cert, now time.Time) bool {
+	return cert.NotAfter != 0 && expired(certNotAfter(cert), now)
+}
+
+// expiredCertFinding describes an expired certificate on an endpoint, including how long
+// ago it expired and whether other endpoints of the host serve a newer certificate.
+// It returns an empty string if the certificate has not expired.
+func expiredCertFinding(host *ssllabs.Host, endpoint ssllabs.Endpoint, now time.Time) string {
+	cert := endpoint.Details.Cert
+	if !certExpired(cert, now) {
+		return ""
+	}
+	finding := fmt.Sprintf("certificate expired %s", relativeTime(certNotAfter(cert), now))
+	// Look for an endpoint of the same host that already serves a valid certificate
+	for _, other := range host.Endpoints {
+		if other.IpAddress == endpoint.IpAddress || other.Details.Cert.NotAfter <= cert.NotAfter || certExpired(other.Details.Cert, now) {
+			continue
+		}
+		return fmt.Sprintf("%s; %s serves a newer certificate valid until %s", finding, other.IpAddress, formatTime(certNotAfter(other.Details.Cert)))
+	}
+	return finding + "; no endpoint serves a newer certificate"
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+func TestDaysLeft(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		want     int
+	}{
+		{"expired an hour ago", now.Add(-time.Hour), -1},
+		{"expired exactly a day ago", now.Add(-expiryDay), -1},
+		{"expired a day and an hour ago", now.Add(-expiryDay - time.Hour), -2},
+		{"expires now", now, 0},
+		{"expires in 23 hours", now.Add(23 * time.Hour), 0},
+		{"expires in exactly a day", now.Add(expiryDay), 1},
+		{"expires in 30 days minus a millisecond", now.Add(days(30) - time.Millisecond), 29},
+	}
+	for _, tt := range tests {
+		if got := daysLeft(tt.notAfter, now); got != tt.want {
+			t.Errorf("%s: daysLeft = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDaysLeftIgnoresTimeZones(t *testing.T) {
+	// A DST change in the zone of either time must not shift the result
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	now := time.Date(2026, 3, 28, 12, 0, 0, 0, madrid)
+	if got := daysLeft(now.Add(2*expiryDay), now); got != 2 {
+		t.Errorf("daysLeft across DST = %d, want 2", got)
+	}
+	if got := daysLeft(now.Add(2*expiryDay).UTC(), now); got != 2 {
+		t.Errorf("daysLeft across zones = %d, want 2", got)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if !expired(now, now) {
+		t.Error("expired at notAfter = false, want true")
+	}
+	if !expired(now.Add(-time.Nanosecond), now) {
+		t.Error("expired before now = false, want true")
+	}
+	if expired(now.Add(time.Nanosecond), now) {
+		t.Error("expired after now = true, want false")
+	}
+}
+
+func TestExpiresWithin(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	window := days(7)
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		want     bool
+	}{
+		{"already expired", now.Add(-time.Hour), true},
+		{"just inside the window", now.Add(window - time.Nanosecond), true},
+		{"exactly at the window", now.Add(window), false},
+		{"outside the window", now.Add(window + time.Hour), false},
+	}
+	for _, tt := range tests {
+		if got := expiresWithin(tt.notAfter, now, window); got != tt.want {
+			t.Errorf("%s: expiresWithin = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCertNotAfterIsUTC(t *testing.T) {
+	defer func(location *time.Location) { timeLocation = location }(timeLocation)
+	for _, zone := range []string{"", "UTC", "Asia/Tokyo", "America/New_York"} {
+		if err := setTimeZone(zone); err != nil {
+			t.Skipf("time zone %q unavailable: %v", zone, err)
+		}
+		notAfter := certNotAfter(ssllabs.Cert{NotAfter: 1767225600000})
+		if notAfter.Location() != time.UTC {
+			t.Errorf("-tz %q: certNotAfter location = %s, want UTC", zone, notAfter.Location())
+		}
+		if want := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC); !notAfter.Equal(want) {
+			t.Errorf("-tz %q: certNotAfter = %s, want %s", zone, notAfter, want)
+		}
+	}
+}
+
+func TestCertExpired(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if certExpired(ssllabs.Cert{}, now) {
+		t.Error("certExpired without a certificate = true, want false")
+	}
+	if !certExpired(ssllabs.Cert{NotAfter: now.UnixMilli()}, now) {
+		t.Error("certExpired at notAfter = false, want true")
+	}
+	if certExpired(ssllabs.Cert{NotAfter: now.Add(time.Millisecond).UnixMilli()}, now) {
+		t.Error("certExpired before notAfter = true, want false")
+	}
+}
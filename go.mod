@@ -1,3 +1,5 @@
-module ssl-checker
+module github.com/SDuque28/ssl-checker-go
 
 go 1.25.5
+
+require golang.org/x/net v0.58.0
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Structs to describe the direct probe of a domain run alongside its SSL Labs assessment
+// with -hybrid. The certificate expiry is in milliseconds since the epoch, like the API's.
+type directProbe struct {
+	Protocol            string   `json:"protocol,omitempty"`
+	LegacyProtocols     []string `json:"legacyProtocols,omitempty"`
+	CipherSuite         string   `json:"cipherSuite,omitempty"`
+	CertificateSubject  string   `json:"certificateSubject,omitempty"`
+	CertificateNotAfter int64    `json:"certificateNotAfter,omitempty"`
+	Critical            []string `json:"critical,omitempty"`
+	Warnings            []string `json:"warnings,omitempty"`
+}
+
+// newDirectProbe summarizes a probe result and its findings
+func newDirectProbe(result probeResult, critical, warnings []string) *directProbe {
+	direct := &directProbe{Critical: critical, Warnings: warnings}
+	if result.Err != nil {
+		return direct
+	}
+	direct.Protocol = tls.VersionName(result.Version)
+	for _, version := range result.LegacyVersions {
+		direct.LegacyProtocols = append(direct.LegacyProtocols, tls.VersionName(version))
+	}
+	direct.CipherSuite = tls.CipherSuiteName(result.CipherSuite)
+	direct.CertificateSubject = result.Certificates[0].Subject.CommonName
+	direct.CertificateNotAfter = result.Certificates[0].NotAfter.UnixMilli()
+	return direct
+}
+
+// startDirectProbe probes the HTTPS service of a domain in the background. Its outcome is
+// logged as soon as it is known, without waiting for the SSL Labs assessment, and then
+// sent on the returned channel to be merged into the results.
+func startDirectProbe(domain string, timeout time.Duration) <-chan *directProbe {
+	done := make(chan *directProbe, 1)
+	go func() {
+		p := &prober{timeout: timeout, expiryWarning: defaultProbeExpiryWarning}
+		result := p.probe(probeTarget{Host: domain, Port: 443})
+		critical, warnings := p.findings(result, time.Now())
+		direct := newDirectProbe(result, critical, warnings)
+		// A single write keeps the block together between progress messages
+		var b strings.Builder
+		displayDirectProbe(&b, direct)
+		logf("%s", b.String())
+		done <- direct
+	}()
+	return done
+}
+
+// displayDirectProbe prints the outcome of a direct probe and its findings
+func displayDirectProbe(w io.Writer, direct *directProbe) {
+	fmt.Fprintf(w, "Direct Probe:\n")
+	if direct.Protocol != "" {
+		fmt.Fprintf(w, "  Protocol: %s\n", direct.Protocol)
+		if len(direct.LegacyProtocols) > 0 {
+			fmt.Fprintf(w, "  Legacy Protocols: %s\n", strings.Join(direct.LegacyProtocols, ", "))
+		}
+		fmt.Fprintf(w, "  Cipher Suite: %s\n", direct.CipherSuite)
+		fmt.Fprintf(w, "  Certificate Subject: %s\n", direct.CertificateSubject)
+		fmt.Fprintf(w, "  Certificate Expires: %s\n", formatTime(time.UnixMilli(direct.CertificateNotAfter)))
+	}
+	displayFindings(w, direct.Critical, direct.Warnings)
+}
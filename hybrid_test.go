@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+func TestDirectProbeInResult(t *testing.T) {
+	p := &prober{timeout: 2 * time.Second}
+	probed := p.probe(startTLSServer(t, tls.VersionTLS11, tls.VersionTLS13))
+	critical, warnings := p.findings(probed, time.Now())
+	direct := newDirectProbe(probed, critical, warnings)
+	if direct.Protocol != "TLS 1.3" || len(direct.LegacyProtocols) != 1 || len(direct.Critical) != 1 {
+		t.Errorf("direct probe = %+v, want TLS 1.3 with TLS 1.1 flagged", direct)
+	}
+	host := &ssllabs.Host{Host: "example.com", Status: "READY"}
+	plain := newResultDocument(host, nil, nil)
+	result := newResultDocument(host, nil, nil).withDirect(direct)
+	if result.ContentHash == plain.ContentHash {
+		t.Error("direct probe: want a different hash")
+	}
+	// The direct probe survives writing and merging the results
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+	var read ResultDocument
+	if err := json.Unmarshal(buf.Bytes(), &read); err != nil {
+		t.Fatal(err)
+	}
+	merged := mergeResults([]*ResultDocument{&read})
+	if merged[0].Direct == nil || merged[0].Direct.Protocol != "TLS 1.3" || merged[0].ContentHash != result.ContentHash {
+		t.Errorf("merged result = %+v, want the direct probe and hash %s", merged[0], result.ContentHash)
+	}
+	// A failed probe only carries its findings
+	failed := newDirectProbe(probeResult{Err: errors.New("connection refused")}, []string{"failed to connect"}, nil)
+	if failed.Protocol != "" || len(failed.Critical) != 1 {
+		t.Errorf("failed probe = %+v, want only the finding", failed)
+	}
+}
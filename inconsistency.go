@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+// certIdentity returns a value identifying the certificate served by an endpoint
+func certIdentity(cert ssllabs.Cert) string {
+	if cert.Sha1Hash != "" {
+		return cert.Sha1Hash
+	}
+	return fmt.Sprintf("%s/%d", cert.Subject, cert.NotAfter)
+}
+
+// protocolList returns the sorted protocol versions supported by an endpoint, e.g. "TLS 1.2, TLS 1.3"
+func protocolList(details ssllabs.EndpointDetails) string {
+	var protocols []string
+	for _, protocol := range details.Protocols {
+		protocols = append(protocols, protocol.Name+" "+protocol.Version)
+	}
+	sort.Strings(protocols)
+	return strings.Join(protocols, ", ")
+}
+
+// endpointInconsistencies returns a finding for each property that differs between the
+// graded endpoints of a host, which usually points at a stale node behind a load balancer
+func endpointInconsistencies(host *ssllabs.Host) []string {
+	properties := []struct {
+		name  string
+		value func(ssllabs.Endpoint) string
+	}{
+		{"certificates", func(e ssllabs.Endpoint) string { return certIdentity(e.Details.Cert) }},
+		{"protocols", func(e ssllabs.Endpoint) string { return protocolList(e.Details) }},
+		{"grades", func(e ssllabs.Endpoint) string { return e.Grade }},
+	}
+	var findings []string
+	for _, property := range properties {
+		// Group the graded endpoints by the value of the property
+		groups := make(map[string][]string)
+		var values []string
+		for _, endpoint := range host.Endpoints {
+			if endpoint.Grade == "" {
+				continue
+			}
+			value := property.value(endpoint)
+			if _, ok := groups[value]; !ok {
+				values = append(values, value)
+			}
+			groups[value] = append(groups[value], endpoint.IpAddress)
+		}
+		if len(values) < 2 {
+			continue
+		}
+		var parts []string
+		for _, value := range values {
+			parts = append(parts, fmt.Sprintf("%s (%s)", strings.Join(groups[value], ", "), value))
+		}
+		findings = append(findings, fmt.Sprintf("endpoints serve different %s: %s", property.name, strings.Join(parts, " vs ")))
+	}
+	return findings
+}
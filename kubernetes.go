@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Control-plane and node ports audited on every Kubernetes node
+var kubernetesPorts = []struct {
+	port int
+	role string
+}{
+	{6443, "kube-apiserver"},
+	{10250, "kubelet"},
+	{2379, "etcd client"},
+	{2380, "etcd peer"},
+}
+
+// Certificates older than this are reported as not rotated
+const kubernetesRotationWarningAge = 365 * expiryDay
+
+// kubernetesTargets returns the control-plane and kubelet services to probe on a node
+func kubernetesTargets(node string) []probeTarget {
+	var targets []probeTarget
+	for _, service := range kubernetesPorts {
+		targets = append(targets, probeTarget{Host: node, Port: service.port, Role: service.role})
+	}
+	return targets
+}
+
+// kubernetesFindings adds the Kubernetes specific checks to the generic probe findings:
+// etcd must require client certificates, kubelets should serve rotated CA-signed
+// certificates, and certificates should not be older than a year. Services that are not
+// listening are skipped since not every node runs every component, but a node with no
+// service listening at all is reported by runKubernetesAudit.
+func kubernetesFindings(p *prober, result probeResult, now time.Time) (critical, warnings []string) {
+	if result.Err != nil {
+		return nil, nil
+	}
+	critical, warnings = p.findings(result, now)
+	leaf := result.Certificates[0]
+	if strings.HasPrefix(result.Role, "etcd") && !result.ClientCertRequested {
+		critical = append(critical, "etcd does not request client certificates")
+	}
+	if result.Role == "kubelet" && bytes.Equal(leaf.RawIssuer, leaf.RawSubject) {
+		warnings = append(warnings, "kubelet serves a self-signed certificate, serving certificate rotation (serverTLSBootstrap) is disabled")
+	}
+	if now.Sub(leaf.NotBefore) > kubernetesRotationWarningAge {
+		warnings = append(warnings, fmt.Sprintf("certificate not rotated since %s", formatTime(leaf.NotBefore)))
+	}
+	return critical, warnings
+}
+
+// runKubernetesAudit implements the k8s-audit subcommand: it probes the kube-apiserver,
+// kubelet and etcd ports of every node of a list. It returns the process exit code.
+func runKubernetesAudit(args []string) int {
+	fs := flag.NewFlagSet("k8s-audit", flag.ExitOnError)
+	caFile := fs.String("ca-file", "", "PEM bundle of the cluster CA certificates to verify against (e.g., /etc/kubernetes/pki/ca.crt)")
+	timeout := fs.Duration("timeout", 5*time.Second, "Timeout for each connection")
+	resultsFile := fs.String("results-file", "", "Write the results to this file instead of stdout")
+	logFile := fs.String("log-file", "", "Append progress and status messages to this file instead of stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ssl-checker k8s-audit [-ca-file ca.crt] [flags] nodes.txt")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	// Redirect the logs if requested
+	closeLog, err := redirectLogs(*logFile)
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	defer closeLog()
+	nodes, err := readDomainList(fs.Arg(0))
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	p := &prober{timeout: *timeout, expiryWarning: defaultProbeExpiryWarning}
+	if *caFile != "" {
+		if p.roots, err = loadCAFile(*caFile); err != nil {
+			logf("Error: %v\n", err)
+			return 1
+		}
+	}
+	now := time.Now()
+	failed := false
+	err = writeResultsWith(*resultsFile, func(w io.Writer) error {
+		for _, node := range nodes {
+			answered := false
+			for _, target := range kubernetesTargets(node) {
+				result := p.probe(target)
+				if result.Err != nil {
+					logf("%s (%s) not reachable: %v\n", target.address(), target.Role, result.Err)
+					continue
+				}
+				answered = true
+				critical, warnings := kubernetesFindings(p, result, now)
+				displayProbeResult(w, result, critical, warnings)
+				failed = failed || len(critical) > 0
+			}
+			// A node answering on no port at all is most likely down or misspelled
+			if !answered {
+				fmt.Fprintf(w, "Node: %s\n", node)
+				displayFindings(w, []string{"no kube-apiserver, kubelet or etcd port answered"}, nil)
+				fmt.Fprintln(w)
+				failed = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	if failed {
+		return exitPolicyViolation
+	}
+	return 0
+}
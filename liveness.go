@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// checkLiveness resolves the domain and checks that at least one of its addresses accepts
+// TCP connections on the given port, so dead hosts are not submitted to SSL Labs
+func checkLiveness(ctx context.Context, domain string, port int, timeout time.Duration) error {
+	// Resolve the domain
+	lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(lookupCtx, domain)
+	if err != nil {
+		return fmt.Errorf("DNS lookup failed: %v", err)
+	}
+	// Try each address until one accepts a connection
+	dialer := &net.Dialer{Timeout: timeout}
+	var lastErr error
+	for _, addr := range addrs {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(addr, strconv.Itoa(port)))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		conn.Close()
+		return nil
+	}
+	return fmt.Errorf("no address of %s accepts connections on port %d: %v", domain, port, lastErr)
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Structs to describe an MTA-STS policy (RFC 8461)
+type mtaSTSPolicy struct {
+	Version string
+	Mode    string
+	MX      []string
+	MaxAge  int
+}
+
+// Structs to describe the mail transport security of a domain
+type mailReport struct {
+	Domain   string
+	MTASTSID string
+	Policy   *mtaSTSPolicy
+	TLSRPT   []string
+	MX       []mxResult
+	Errors   []string
+	Warnings []string
+}
+
+// Structs to describe the STARTTLS check of a single MX host
+type mxResult struct {
+	Host          string
+	Preference    uint16
+	MatchesPolicy bool
+	StartTLSError error
+	TLS           *tls.ConnectionState
+	// VerifyError is set when the certificate does not verify for the host name
+	VerifyError error
+	// TLSA holds the TLSA records of the host, DANEVerified is set when the certificate
+	// matched one of them
+	TLSA         *tlsaLookup
+	DANEVerified bool
+}
+
+// checkMailDomain validates the MTA-STS and TLS-RPT records of a mail domain and checks
+// that every MX host offers STARTTLS with a current protocol and a certificate valid for
+// its name or matching its DNSSEC-validated TLSA records (DANE)
+func checkMailDomain(domain string, timeout time.Duration) *mailReport {
+	report := &mailReport{Domain: domain}
+	// MTA-STS DNS record and policy
+	id, err := lookupMTASTSRecord(domain)
+	switch {
+	case errors.Is(err, errNoRecord):
+		report.Warnings = append(report.Warnings, "no MTA-STS record published")
+	case err != nil:
+		report.Errors = append(report.Errors, err.Error())
+	default:
+		report.MTASTSID = id
+		policy, err := fetchMTASTSPolicy(domain, timeout)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		} else {
+			report.Policy = policy
+			if policy.Mode == "testing" {
+				report.Warnings = append(report.Warnings, "MTA-STS policy is in testing mode")
+			}
+		}
+	}
+	// TLS-RPT DNS record
+	rua, err := lookupTLSRPTRecord(domain)
+	switch {
+	case errors.Is(err, errNoRecord):
+		report.Warnings = append(report.Warnings, "no TLS-RPT record published")
+	case err != nil:
+		report.Errors = append(report.Errors, err.Error())
+	default:
+		report.TLSRPT = rua
+	}
+	// MX hosts
+	mxs, err := net.LookupMX(domain)
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("MX lookup failed: %v", err))
+		return report
+	}
+	sort.Slice(mxs, func(i, j int) bool { return mxs[i].Pref < mxs[j].Pref })
+	for _, mx := range mxs {
+		host := strings.TrimSuffix(mx.Host, ".")
+		result := mxResult{Host: host, Preference: mx.Pref}
+		if report.Policy != nil {
+			result.MatchesPolicy = report.Policy.matches(host)
+			if !result.MatchesPolicy && report.Policy.Mode == "enforce" {
+				report.Errors = append(report.Errors, fmt.Sprintf("MX %s is not listed in the enforced MTA-STS policy", host))
+			}
+		}
+		result.TLS, result.StartTLSError = checkStartTLS(host, timeout)
+		if result.StartTLSError != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("MX %s: %v", host, result.StartTLSError))
+			report.MX = append(report.MX, result)
+			continue
+		}
+		report.checkMXCertificate(&result, timeout, time.Now())
+		report.Warnings = append(report.Warnings, mxTLSWarnings(host, result.TLS, time.Now())...)
+		report.MX = append(report.MX, result)
+	}
+	return report
+}
+
+// checkMXCertificate authenticates the certificate of an MX host with DANE when it
+// publishes DNSSEC-validated TLSA records, and with the system roots otherwise
+func (report *mailReport) checkMXCertificate(result *mxResult, timeout time.Duration, now time.Time) {
+	host, certs := result.Host, result.TLS.PeerCertificates
+	result.VerifyError = verifyMXCertificate(host, certs, now)
+	tlsa, err := lookupTLSA(host, 25, timeout)
+	switch {
+	case err != nil:
+		report.Warnings = append(report.Warnings, fmt.Sprintf("MX %s: %v", host, err))
+	case len(tlsa.Records) == 0:
+	case !tlsa.Secure:
+		report.Warnings = append(report.Warnings, fmt.Sprintf("MX %s publishes TLSA records the resolver did not validate with DNSSEC, DANE is not in effect", host))
+	default:
+		result.TLSA = tlsa
+		usable, matched := daneVerify(tlsa.Records, host, certs, now)
+		switch {
+		case usable == 0:
+			report.Warnings = append(report.Warnings, fmt.Sprintf("MX %s only publishes TLSA records unusable for SMTP (PKIX-TA, PKIX-EE)", host))
+		case matched:
+			result.DANEVerified = true
+		default:
+			report.Errors = append(report.Errors, fmt.Sprintf("MX %s certificate matches none of its TLSA records", host))
+			return
+		}
+	}
+	// DANE takes precedence over the system roots, e.g. for self-signed DANE-EE certificates
+	if result.VerifyError != nil && !result.DANEVerified {
+		report.Errors = append(report.Errors, fmt.Sprintf("MX %s certificate does not verify: %v", host, result.VerifyError))
+	}
+}
+
+// verifyMXCertificate checks the certificate chain of an MX host against the system roots
+// and the host name
+func verifyMXCertificate(host string, certs []*x509.Certificate, now time.Time) error {
+	if len(certs) == 0 {
+		return fmt.Errorf("no certificate presented")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{DNSName: host, Intermediates: intermediates, CurrentTime: now})
+	return err
+}
+
+// Number of days before certificate expiry from which an MX certificate is flagged
+const mxExpiryWarningDays = 14
+
+// mxTLSWarnings flags outdated protocol versions and certificates close to expiry on an MX host
+func mxTLSWarnings(host string, state *tls.ConnectionState, now time.Time) []string {
+	var warnings []string
+	if state.Version < tls.VersionTLS12 {
+		warnings = append(warnings, fmt.Sprintf("MX %s negotiated %s, TLS 1.2 or later is expected", host, tls.VersionName(state.Version)))
+	}
+	if len(state.PeerCertificates) > 0 {
+		notAfter := state.PeerCertificates[0].NotAfter
+		if expiresWithin(notAfter, now, days(mxExpiryWarningDays)) {
+			warnings = append(warnings, fmt.Sprintf("MX %s certificate expires %s", host, relativeTime(notAfter, now)))
+		}
+	}
+	return warnings
+}
+
+// errNoRecord is returned when a domain publishes no record of the requested kind
+var errNoRecord = errors.New("no record")
+
+// lookupTaggedTXT returns the single TXT record of name starting with the version tag
+func lookupTaggedTXT(name, version string) (string, error) {
+	records, err := net.LookupTXT(name)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return "", errNoRecord
+		}
+		return "", fmt.Errorf("TXT lookup of %s failed: %v", name, err)
+	}
+	var matching []string
+	for _, record := range records {
+		if strings.HasPrefix(record, version+";") || record == version {
+			matching = append(matching, record)
+		}
+	}
+	switch len(matching) {
+	case 0:
+		return "", errNoRecord
+	case 1:
+		return matching[0], nil
+	default:
+		return "", fmt.Errorf("%s publishes %d %s records, exactly one is allowed", name, len(matching), version)
+	}
+}
+
+// recordFields parses the key=value fields of a tagged TXT record
+func recordFields(record string) map[string]string {
+	fields := make(map[string]string)
+	for _, field := range strings.Split(record, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if ok {
+			fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
+
+// lookupMTASTSRecord returns the policy ID published in the _mta-sts TXT record
+func lookupMTASTSRecord(domain string) (string, error) {
+	record, err := lookupTaggedTXT("_mta-sts."+domain, "v=STSv1")
+	if err != nil {
+		return "", err
+	}
+	id := recordFields(record)["id"]
+	if id == "" {
+		return "", fmt.Errorf("MTA-STS record has no id")
+	}
+	return id, nil
+}
+
+// lookupTLSRPTRecord returns the reporting URIs published in the _smtp._tls TXT record
+func lookupTLSRPTRecord(domain string) ([]string, error) {
+	record, err := lookupTaggedTXT("_smtp._tls."+domain, "v=TLSRPTv1")
+	if err != nil {
+		return nil, err
+	}
+	var rua []string
+	for _, uri := range strings.Split(recordFields(record)["rua"], ",") {
+		uri = strings.TrimSpace(uri)
+		if !strings.HasPrefix(uri, "mailto:") && !strings.HasPrefix(uri, "https:") {
+			return nil, fmt.Errorf("TLS-RPT record has an invalid rua %q", uri)
+		}
+		rua = append(rua, uri)
+	}
+	return rua, nil
+}
+
+// fetchMTASTSPolicy fetches and parses the MTA-STS policy of a domain
+func fetchMTASTSPolicy(domain string, timeout time.Duration) (*mtaSTSPolicy, error) {
+	// Policy hosts must answer directly, redirects are not followed
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get("https://mta-sts." + domain + "/.well-known/mta-sts.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch MTA-STS policy: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("MTA-STS policy returned non-OK status: %s", resp.Status)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		return nil, fmt.Errorf("MTA-STS policy is served as %q instead of text/plain", resp.Header.Get("Content-Type"))
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MTA-STS policy: %v", err)
+	}
+	return parseMTASTSPolicy(string(body))
+}
+
+// parseMTASTSPolicy parses and validates the key: value lines of an MTA-STS policy
+func parseMTASTSPolicy(text string) (*mtaSTSPolicy, error) {
+	policy := &mtaSTSPolicy{MaxAge: -1}
+	for _, line := range strings.Split(text, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "version":
+			policy.Version = value
+		case "mode":
+			policy.Mode = value
+		case "mx":
+			policy.MX = append(policy.MX, value)
+		case "max_age":
+			maxAge, err := strconv.Atoi(value)
+			if err != nil || maxAge < 0 {
+				return nil, fmt.Errorf("MTA-STS policy has an invalid max_age %q", value)
+			}
+			policy.MaxAge = maxAge
+		}
+	}
+	if policy.Version != "STSv1" {
+		return nil, fmt.Errorf("MTA-STS policy has an invalid version %q", policy.Version)
+	}
+	if policy.Mode != "enforce" && policy.Mode != "testing" && policy.Mode != "none" {
+		return nil, fmt.Errorf("MTA-STS policy has an invalid mode %q", policy.Mode)
+	}
+	if policy.MaxAge < 0 {
+		return nil, fmt.Errorf("MTA-STS policy has no max_age")
+	}
+	if len(policy.MX) == 0 && policy.Mode != "none" {
+		return nil, fmt.Errorf("MTA-STS policy lists no mx hosts")
+	}
+	return policy, nil
+}
+
+// matches reports whether an MX host is allowed by the policy. A leading "*." matches
+// exactly one label.
+func (p *mtaSTSPolicy) matches(host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range p.MX {
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			label, rest, found := strings.Cut(host, ".")
+			if found && label != "" && rest == suffix {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// checkStartTLS connects to an MX host on port 25 and upgrades the session with STARTTLS.
+// The certificate is verified separately since it may be authenticated with DANE.
+func checkStartTLS(host string, timeout time.Duration) (*tls.ConnectionState, error) {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, "25"), timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect on port 25: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SMTP handshake failed: %v", err)
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); !ok {
+		return nil, fmt.Errorf("STARTTLS not offered")
+	}
+	// Legacy protocols are allowed so MX hosts only speaking them are flagged, not failed
+	if err := client.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS10, InsecureSkipVerify: true}); err != nil {
+		return nil, fmt.Errorf("STARTTLS failed: %v", err)
+	}
+	state, _ := client.TLSConnectionState()
+	client.Quit()
+	return &state, nil
+}
+
+// displayMailReport prints the mail transport security report to the given writer
+func displayMailReport(w io.Writer, report *mailReport) {
+	fmt.Fprintf(w, "Mail Transport Security Results:\n")
+	fmt.Fprintf(w, "Domain: %s\n", report.Domain)
+	if report.Policy != nil {
+		fmt.Fprintf(w, "MTA-STS: mode %s, max_age %d, id %s\n", report.Policy.Mode, report.Policy.MaxAge, report.MTASTSID)
+		fmt.Fprintf(w, "  Allowed MX: %s\n", strings.Join(report.Policy.MX, ", "))
+	} else {
+		fmt.Fprintf(w, "MTA-STS: none\n")
+	}
+	if len(report.TLSRPT) > 0 {
+		fmt.Fprintf(w, "TLS-RPT: %s\n", strings.Join(report.TLSRPT, ", "))
+	} else {
+		fmt.Fprintf(w, "TLS-RPT: none\n")
+	}
+	for i, mx := range report.MX {
+		fmt.Fprintf(w, "MX %d: %s (preference %d)\n", i+1, mx.Host, mx.Preference)
+		if report.Policy != nil {
+			fmt.Fprintf(w, "  Matches MTA-STS Policy: %t\n", mx.MatchesPolicy)
+		}
+		if mx.StartTLSError != nil {
+			fmt.Fprintf(w, "  STARTTLS: failed (%v)\n", mx.StartTLSError)
+			continue
+		}
+		fmt.Fprintf(w, "  STARTTLS: ok\n")
+		if mx.VerifyError == nil {
+			fmt.Fprintf(w, "  Certificate: valid for %s\n", mx.Host)
+		} else {
+			fmt.Fprintf(w, "  Certificate: not valid for %s (%v)\n", mx.Host, mx.VerifyError)
+		}
+		switch {
+		case mx.TLSA == nil:
+			fmt.Fprintf(w, "  DANE: none\n")
+		case mx.DANEVerified:
+			fmt.Fprintf(w, "  DANE: verified (%s)\n", strings.Join(tlsaUsages(mx.TLSA.Records), ", "))
+		default:
+			fmt.Fprintf(w, "  DANE: not verified (%s)\n", strings.Join(tlsaUsages(mx.TLSA.Records), ", "))
+		}
+		fmt.Fprintf(w, "  Protocol: %s\n", tls.VersionName(mx.TLS.Version))
+		fmt.Fprintf(w, "  Cipher Suite: %s\n", tls.CipherSuiteName(mx.TLS.CipherSuite))
+		if len(mx.TLS.PeerCertificates) > 0 {
+			cert := mx.TLS.PeerCertificates[0]
+			fmt.Fprintf(w, "  Certificate Subject: %s\n", cert.Subject.CommonName)
+			fmt.Fprintf(w, "  Certificate Issuer: %s\n", cert.Issuer.CommonName)
+			fmt.Fprintf(w, "  Certificate Expires: %s\n", formatTime(cert.NotAfter))
+		}
+	}
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(w, "Warning: %s\n", warning)
+	}
+	for _, err := range report.Errors {
+		fmt.Fprintf(w, "Error: %s\n", err)
+	}
+	verdict := "PASS"
+	if len(report.Errors) > 0 {
+		verdict = "FAIL"
+	}
+	fmt.Fprintf(w, "Verdict: %s\n", verdict)
+}
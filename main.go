@@ -1,242 +1,451 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
+	"strings"
 	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
 )
-// Structs to parse Info JSON responses from SSL Labs API
-type Info struct {
-	Version      		 string `json:"version"`
-	CriteriaVersion 	 string `json:"criteriaVersion"`
-	MaxAssessments 		 int    `json:"maxAssessments"`
-	CurrentAssessments 	 int    `json:"currentAssessments"`
-	NewAssessmentCoolOff int64  `json:"newAssessmentCoolOff"`
-	Messages   		   []string `json:"messages"`
-}
-// Structs to parse Host JSON responses from SSL Labs API
-type Host struct {
-	Host 	  		string 	 `json:"host"`
-	Port      		int    	 `json:"port"`
-	Protocol  		string 	 `json:"protocol"`
-	IsPublic  		bool   	 `json:"isPublic"`
-	Status    		string 	 `json:"status"`
-	StatusMessage 	string 	 `json:"statusMessage"`
-	StartTime  		int64  	 `json:"startTime"`
-	TestTime   		int64  	 `json:"testTime"`
-	EngineVersion 	string 	 `json:"engineVersion"`
-	CriteriaVersion string 	 `json:"criteriaVersion"`
-	Endpoints     []Endpoint `json:"endpoints"`
-}
-// Structs to parse Endpoint JSON responses from SSL Labs API
-type Endpoint struct {
-	IpAddress         string `json:"ipAddress"`
-	ServerName        string `json:"serverName"`
-	StatusMessage     string `json:"statusMessage"`
-	StatusDetails  	  string `json:"statusDetails"`
-	Grade             string `json:"grade"`
-	GradeTrustIgnored string `json:"gradeTrustIgnored"`
-	HasWarnings       bool   `json:"hasWarnings"`
-	Progress          int    `json:"progress"`
-	Duration          int    `json:"duration"`
-	Eta 		 	  int    `json:"eta"`
-}
-// SSLClient struct to interact with SSL Labs API as a client
-type SSLClient struct {
-	baseurl   string
-	client	*http.Client
+// progressPrinter displays the progress of each endpoint while waiting for an assessment
+type progressPrinter struct {
+	i, endpoint int
+	started     bool
 }
-// NewSSLClient initializes and returns a new SSLClient
-func NewSSLClient() *SSLClient {
-	return &SSLClient{
-		baseurl: "https://api.ssllabs.com/api/v2",
-		client: &http.Client{Timeout: 30*time.Second},
-	}
-}
-// CheckApiStatus checks the status of the SSL Labs API
-func (s *SSLClient) CheckApiStatus() (*Info, error) {
-	// Make a GET request to the /info endpoint
-	resp, err := s.client.Get(s.baseurl + "/info")
-	if err != nil {
-		return nil, fmt.Errorf("failed to reach SSL Labs API: %v", err)
+// print displays the progress of the endpoint currently being assessed
+func (p *progressPrinter) print(host *ssllabs.Host) {
+	// Nothing to display until the endpoints are known
+	if len(host.Endpoints) == 0 {
+		return
 	}
-	
-	defer resp.Body.Close()
-	// Check for non-200 status codes
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned non-OK status: %s", resp.Status)
-	}
-	// Read and parse the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read API response: %v", err)
-	}
-	// Unmarshal JSON into Info struct
-	var info Info
-	if err := json.Unmarshal(body, &info); err != nil {
-		return nil, fmt.Errorf("failed to parse API response: %v", err)
+	// Display progress for each endpoint
+	if !p.started || host.Endpoints[p.i].Progress == 100 {
+		if host.Endpoints[p.i].Progress == 100 {
+			logf("      %s:%d - %d%%\n", host.Endpoints[p.i].IpAddress, host.Port, host.Endpoints[p.i].Progress)
+			if p.i+1 < len(host.Endpoints) {
+				p.i++
+			}
+		}
+		if p.endpoint < len(host.Endpoints)+1 {
+			logf("\n----- PROGRESS ON ENDPOINT %d ----- \n", p.endpoint)
+			p.endpoint++
+		}
+		p.started = true
 	}
-	// Display API status information
-	fmt.Println("SSL Labs API is reachable.")
-	fmt.Printf("Criteria Version: %s\n", info.CriteriaVersion)
-	fmt.Printf("Concurrent assessments allowed: %d\n", info.MaxAssessments)
-	fmt.Printf("Current assessments: %d\n", info.CurrentAssessments)
-	return &info, nil
+	logf("      %s:%d - %d%%\n", host.Endpoints[p.i].IpAddress, host.Port, host.Endpoints[p.i].Progress)
 }
-// StartAssessment initiates a new SSL/TLS assessment for the given domain
-func (s *SSLClient) StartAssessment(domain string, publish bool) (*Host, error) {
-	url := fmt.Sprintf("%s/analyze?host=%s&all=done&startNew=on", s.baseurl, domain)
-	// Append publish parameter if needed
-	if publish {
-		url += "&publish=on"
-	}
-	// Make a GET request to start the assessment
-	resp, err := s.client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to start assessment: %v", err)
-	}
-	defer resp.Body.Close()
-	// Read and parse the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read assessment response: %v", err)
+// protocolName returns a display name for the application protocol reported by the API
+func protocolName(protocol string) string {
+	switch protocol {
+	case "":
+		return "unknown protocol"
+	case "http":
+		// SSL Labs reports HTTPS assessments with the plain HTTP protocol name
+		return "HTTPS"
+	default:
+		return strings.ToUpper(protocol) + " over TLS"
 	}
-	// Unmarshal JSON into Host struct
-	var host Host
-	if err := json.Unmarshal(body, &host); err != nil {
-		return nil, fmt.Errorf("failed to parse assessment response: %v", err)
-	}
-	return &host, nil
 }
-// CheckAssessmentStatus checks the status of an ongoing assessment for the given domain
-func (s *SSLClient) CheckAssessmentStatus(domain string) (*Host, error) {
-	url := fmt.Sprintf("%s/analyze?host=%s&all=done", s.baseurl, domain)
-	// Make a GET request to check the assessment status
-	resp, err := s.client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check assessment status: %v", err)
+// displayResults prints the assessment results to the given writer
+func displayResults(w io.Writer, result *ResultDocument) {
+	host := result.Host
+	fmt.Fprintf(w, "Assessment Results:\n")
+	fmt.Fprintf(w, "Domain: %s\n", host.Host)
+	// Display the assessed service when the API reports it
+	if host.Port != 0 {
+		fmt.Fprintf(w, "Service: %s on port %d\n", protocolName(host.Protocol), host.Port)
 	}
-	defer resp.Body.Close()
-	// Read and parse the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read assessment status response: %v", err)
-	}
-	// Unmarshal JSON into Host struct
-	var host Host
-	if err := json.Unmarshal(body, &host); err != nil {
-		return nil, fmt.Errorf("failed to parse assessment status response: %v", err)
-	}
-	return &host, nil
-}
-// WaitForAssessment polls the assessment status until it is complete
-func (s *SSLClient) WaitForAssessment(domain string) (*Host, error) {
-	fmt.Println("Waiting for assessment to complete...")
-	i, endpoint := 0, 1
-	flag := false
-	// Poll every 10 seconds until the assessment is complete
-	for {
-		// Check the current assessment status
-		host, err := s.CheckAssessmentStatus(domain)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check assessment status: %v", err)
-		}
-		// Display progress for each endpoint
-		if !flag || host.Endpoints[i].Progress == 100 {
-			if host.Endpoints[i].Progress == 100 {
-				fmt.Printf("      %s:%d - %d%%\n",host.Endpoints[i].IpAddress, host.Port, host.Endpoints[i].Progress)
-				if i+1 < len(host.Endpoints) {
-					i++
-				}				
-			}
-			if endpoint < len(host.Endpoints) + 1{
-				fmt.Printf("\n----- PROGRESS ON ENDPOINT %d ----- \n", endpoint)
-				endpoint++
-			}
-			flag = true
-		}
-		fmt.Printf("      %s:%d - %d%%\n",host.Endpoints[i].IpAddress, host.Port, host.Endpoints[i].Progress)
-		// If the status is READY or ERROR, return the host
-		if host.Status == "READY" || host.Status == "ERROR" {
-			fmt.Println()
-			return host, nil
-		}
-		time.Sleep(10 * time.Second)
+	fmt.Fprintf(w, "Status: %s\n", host.Status)
+	// Display the notes attached to the scan
+	for _, note := range result.Notes {
+		fmt.Fprintf(w, "Note: %s\n", note)
 	}
-}
-// displayResults prints the assessment results to the console
-func displayResults(host *Host) {
-	fmt.Printf("Assessment Results:\n")
-	fmt.Printf("Domain: %s\n", host.Host)
-	fmt.Printf("Status: %s\n", host.Status)
 	// Handle different assessment statuses
 	switch host.Status {
 		// Display results if the assessment is ready
 		case "READY":
-			fmt.Printf("Test completed: %s\n", time.Unix(host.TestTime/1000, 0).Format("2006-01-02 15:04:05"))
+			now := time.Now()
+			fmt.Fprintf(w, "Test completed: %s\n", formatTime(time.UnixMilli(host.TestTime)))
 			// Iterate through each endpoint and display its results
 			for i,endpoint := range host.Endpoints {
-				fmt.Printf("Endpoint %d:\n", i+1)
-				fmt.Printf("  IP Address: %s\n", endpoint.IpAddress)
-				fmt.Printf("  Grade: %s\n", endpoint.Grade)
-				fmt.Printf("  Status Message: %s\n", endpoint.StatusMessage)
-				fmt.Printf("  Has Warnings: %t\n", endpoint.HasWarnings)
-				fmt.Println()
+				fmt.Fprintf(w, "Endpoint %d:\n", i+1)
+				fmt.Fprintf(w, "  IP Address: %s\n", endpoint.IpAddress)
+				fmt.Fprintf(w, "  Grade: %s\n", endpoint.Grade)
+				fmt.Fprintf(w, "  Status Message: %s\n", endpoint.StatusMessage)
+				if endpoint.Details.Cert.NotAfter != 0 {
+					fmt.Fprintf(w, "  Certificate Expires: %s\n", formatTime(certNotAfter(endpoint.Details.Cert)))
+				}
+				// Expired certificates are critical regardless of the grade
+				if finding := expiredCertFinding(host, endpoint, now); finding != "" {
+					fmt.Fprintf(w, "  CRITICAL: %s\n", finding)
+				}
+				fmt.Fprintf(w, "  Has Warnings: %t\n", endpoint.HasWarnings)
+				// List the issues behind the warnings
+				for _, warning := range endpointWarnings(endpoint) {
+					fmt.Fprintf(w, "    - %s\n", warning)
+				}
+				// List the gaps against the compliance baseline
+				if gaps := complianceGaps(endpoint); len(gaps) > 0 {
+					fmt.Fprintf(w, "  Compliance Gaps: %s\n", strings.Join(gaps, ", "))
+				}
+				fmt.Fprintf(w, "  Risk Score: %d/100\n", riskScore(endpoint, now))
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "Domain Risk Score: %d/100\n", domainRiskScore(host, now))
+			// Flag endpoints that disagree with each other
+			if findings := endpointInconsistencies(host); len(findings) > 0 {
+				fmt.Fprintf(w, "Inconsistent Endpoints:\n")
+				for _, finding := range findings {
+					fmt.Fprintf(w, "  - %s\n", finding)
+				}
 			}
 		// Display error message if the assessment failed
 		case "ERROR":
-			fmt.Printf("Assessment failed: %s\n", host.StatusMessage)
+			fmt.Fprintf(w, "Assessment failed: %s\n", host.StatusMessage)
+	}
+	// List the insecure subresources found on the homepage
+	if len(result.MixedContent) > 0 {
+		fmt.Fprintf(w, "Mixed Content:\n")
+		for _, url := range result.MixedContent {
+			fmt.Fprintf(w, "  - %s\n", url)
+		}
+	}
+	// Display the direct probe run alongside the assessment
+	if result.Direct != nil {
+		displayDirectProbe(w, result.Direct)
 	}
 }
+// exitUpstreamFailure exits after an SSL Labs failure, with code 0 in soft-fail mode when
+// the API is unavailable or over quota so pipelines are not blocked by upstream outages
+func exitUpstreamFailure(err error, softFail bool) {
+	if softFail && (errors.Is(err, ssllabs.ErrUnavailable) || errors.Is(err, ssllabs.ErrMaxAssessments)) {
+		logln("SSL Labs is unavailable; exiting successfully because of -soft-fail.")
+		os.Exit(0)
+	}
+	os.Exit(1)
+}
 // main function to parse command-line arguments and run the assessment
 func main() {
+	// Dispatch subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "prefetch":
+			os.Exit(runPrefetch(os.Args[2:]))
+		case "start":
+			os.Exit(runStart(os.Args[2:]))
+		case "collect":
+			os.Exit(runCollect(os.Args[2:]))
+		case "report":
+			os.Exit(runReport(os.Args[2:]))
+		case "k8s-audit":
+			os.Exit(runKubernetesAudit(os.Args[2:]))
+		case "probe":
+			os.Exit(runProbe(os.Args[2:]))
+		case "pki-check":
+			os.Exit(runPKICheck(os.Args[2:]))
+		case "validate-targets":
+			os.Exit(runValidateTargets(os.Args[2:]))
+		case "plan":
+			os.Exit(runPlanCommand(os.Args[2:]))
+		case "compare-origin":
+			os.Exit(runCompareOrigin(os.Args[2:]))
+		}
+	}
 	// Define command-line flags
 	domain := flag.String("domain", "", "Domain to check (e.g., example.com)")
 	publish := flag.Bool("publish", false, "Publish results on SSL Labs board")
+	assumeYes := flag.Bool("yes", false, "Publish without asking for confirmation (required with -publish when not run from a terminal)")
+	verifyToken := flag.String("verify-ownership", "", "With -publish, refuse to publish unless the domain publishes this token in a TXT record (ssl-checker-verification=TOKEN) or at /.well-known/ssl-checker-verification")
+	evidence := flag.String("evidence", "", "Write a zipped evidence bundle of the scan to this file (e.g., out.zip)")
+	manifest := flag.String("manifest", "", "Write a manifest of the scan inputs and engine versions to this file (e.g., manifest.json)")
+	progressJSON := flag.Bool("progress-json", false, "Emit progress events as JSON lines on stderr while waiting")
+	progressFd := flag.Int("progress-fd", 0, "Emit progress events as JSON lines to this file descriptor instead of stderr")
+	minGrade := flag.String("min-grade", "", "Exit with code 2 if any endpoint is graded below this grade (e.g., A)")
+	gradeModifiers := flag.String("grade-modifiers", gradeModifiersStrict, "How -min-grade treats +/- modifiers: strict (A- is below A) or ignore (A+, A and A- are equal)")
+	timeZone := flag.String("tz", "", "Time zone for report timestamps, as an IANA name (e.g., UTC, Europe/Madrid); defaults to local time")
+	timeFormatFlag := flag.String("time-format", timeFormatDefault, "Format for report timestamps: default, rfc3339, unix or relative")
+	webhook := flag.String("webhook", "", "POST the results as a CloudEvent to this URL when the scan completes")
+	var expiryAlerts expiryLadder
+	flag.Var(&expiryAlerts, "expiry-alert", "POST a CloudEvent to URL when a certificate expires within DAYS, as DAYS=URL; repeat for an escalation ladder (e.g., 30=https://chat... -expiry-alert 7=https://pager...)")
+	output := flag.String("output", outputText, "Results format: text, json, zabbix (zabbix_sender JSON) or checkmk (local check)")
+	softFail := flag.Bool("soft-fail", false, "Exit with code 0 when SSL Labs is unavailable or over quota, still failing on policy violations")
+	resultsFile := flag.String("results-file", "", "Write the results to this file instead of stdout")
+	logFile := flag.String("log-file", "", "Append progress and status messages to this file instead of stderr")
+	maxResultAge := flag.Duration("max-result-age", 0, "Reuse a cached SSL Labs result newer than this age (e.g., 24h) instead of starting a new assessment")
+	bestEffort := flag.String("best-effort", "", "Comma-separated IPs or CIDR ranges of endpoints to report but ignore in policy evaluation (e.g., CDN nodes)")
+	precheck := flag.Bool("precheck", false, "Check that the domain resolves and accepts connections on port 443 before submitting it to SSL Labs")
+	progressFile := flag.String("progress-file", "", "Keep this file updated with the assessment progress as JSON while waiting")
+	hybrid := flag.Bool("hybrid", false, "Probe the domain directly while the SSL Labs assessment runs, logging its protocols and certificate at once and merging them into the results")
+	mixedContent := flag.Bool("mixed-content", false, "Fetch the homepage over HTTPS and report http:// subresources (mixed content) as policy violations")
+	mailDomain := flag.String("mail-domain", "", "Check the mail transport security of this domain (MTA-STS, TLS-RPT, STARTTLS and DANE on its MX hosts) instead of running an SSL Labs assessment")
+	baselineFile := flag.String("baseline", "", "JSON result of an earlier scan of the domain (from -output json) to compare against")
+	onlyRegressions := flag.Bool("only-regressions", false, "With -baseline, exit with code 2 only on findings that are new or worse than in the baseline instead of on every policy violation")
+	flag.Var(&scanNotes, "note", "Attach a free-text note to the scan, shown in the report and recorded in the manifest and evidence bundle (repeatable)")
 	help := flag.Bool("help", false, "Show help")
 	flag.Parse()
-	// Show help if requested or if domain is not provided
-	if *help || *domain == "" {
+	// Show help if requested or if no domain is provided
+	if *help || (*domain == "" && *mailDomain == "") {
 		fmt.Println("SSL Labs API Checker")
 		fmt.Println("Usage:")
 		flag.PrintDefaults()
+		fmt.Println("Subcommands:")
+		fmt.Println("  prefetch domains.txt    Start assessments for a domain list without waiting for results")
+		fmt.Println("  start example.com       Submit an assessment and print its handle")
+		fmt.Println("  collect handle.json     Fetch the results of a submitted assessment (exit code 3 if not ready)")
+		fmt.Println("  report merge a.json ... Merge -output json result files, keeping the latest result per host")
+		fmt.Println("  report risk a.json ...  Print domain risk scores and the org-level rollup (-previous for the trend)")
+		fmt.Println("  k8s-audit nodes.txt     Probe kube-apiserver, kubelet and etcd TLS on every node of a list")
+		fmt.Println("  probe hosts.txt         Probe the TLS certificates of services directly (-service ldaps, ...)")
+		fmt.Println("  pki-check endpoints.txt Check CA web enrollment, OCSP and CRL endpoints")
+		fmt.Println("  validate-targets hosts.txt Check a host list before scanning it and print the cleaned list")
+		fmt.Println("  plan domains.txt        Estimate run time, batches and cool-off waits for a domain list")
+		fmt.Println("  compare-origin edge.example.com origin Flag protocols the origin accepts but its CDN edge refuses")
 		os.Exit(0)
 	}
-	// Initialize SSLClient
-	sslClient := NewSSLClient()
-	// Check API status
-	info, err := sslClient.CheckApiStatus()
+	// Validate the grade policy, time and output settings before starting anything
+	scanPolicy, err := newPolicy(*minGrade, *gradeModifiers, *bestEffort)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		logf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := setTimeZone(*timeZone); err != nil {
+		logf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := setTimeFormat(*timeFormatFlag); err != nil {
+		logf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := validateOutput(*output); err != nil {
+		logf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	// The mail transport report only has a text rendering
+	if *mailDomain != "" && *output != outputText {
+		logf("Error: -mail-domain only supports -output %s\n", outputText)
 		os.Exit(1)
 	}
-	// Check if maximum concurrent assessments is reached
-	if info.CurrentAssessments >= info.MaxAssessments {
-		fmt.Println("Maximum number of concurrent assessments reached. Please try again later.")
+	// Load the baseline up front so a bad file fails before the assessment
+	var baseline *ResultDocument
+	if *onlyRegressions && *baselineFile == "" {
+		logln("Error: -only-regressions requires -baseline")
 		os.Exit(1)
 	}
-	fmt.Printf("Checking SSL/TLS for domain: %s\n", *domain)
-	// Start a new assessment
-	fmt.Println("Starting Assessment ....")
-	host, err := sslClient.StartAssessment(*domain, *publish)
+	if *baselineFile != "" {
+		if baseline, err = readBaseline(*baselineFile, *domain); err != nil {
+			logf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	// Redirect the logs if requested
+	closeLog, err := redirectLogs(*logFile)
 	if err != nil {
-		fmt.Printf("Error starting assessment: %v\n", err)
+		logf("Error: %v\n", err)
 		os.Exit(1)
 	}
-	// Display initial assessment status
-	fmt.Printf("Assessment started for %s\n", host.Host)
-	if host.Status != "READY" && host.Status != "ERROR" {
-		// Wait for the assessment to complete
-		host, err = sslClient.WaitForAssessment(*domain)
+	defer closeLog()
+	// Check a mail domain instead of running an assessment
+	if *mailDomain != "" {
+		logf("Checking mail transport security for domain: %s\n", *mailDomain)
+		report := checkMailDomain(*mailDomain, 10*time.Second)
+		err := writeResultsWith(*resultsFile, func(w io.Writer) error {
+			displayMailReport(w, report)
+			return nil
+		})
 		if err != nil {
-			fmt.Printf("Error waiting for assessment: %v\n", err)
+			logf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(report.Errors) > 0 {
+			os.Exit(exitPolicyViolation)
+		}
+		return
+	}
+	started := time.Now()
+	ctx := context.Background()
+	// Make sure publishing is intended before anything is submitted
+	if *publish {
+		if err := confirmPublish([]string{*domain}, *assumeYes); err != nil {
+			logf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	// Refuse to publish results for domains whose ownership is not verified
+	if *publish && *verifyToken != "" {
+		if err := verifyOwnership(ctx, *domain, *verifyToken, 10*time.Second); err != nil {
+			logf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		logf("Ownership of %s verified, results will be published\n", *domain)
+	}
+	// Skip hosts that are down before spending an assessment on them
+	if *precheck {
+		if err := checkLiveness(ctx, *domain, 443, 5*time.Second); err != nil {
+			logf("Skipping %s: %v\n", *domain, err)
 			os.Exit(1)
 		}
 	}
+	// Initialize the SSL Labs client
+	sslClient := ssllabs.NewClient()
+	// Route structured progress events if requested
+	var progress io.Writer
+	if *progressFd > 0 {
+		progress = os.NewFile(uintptr(*progressFd), "progress")
+	} else if *progressJSON {
+		progress = os.Stderr
+	}
+	printer := &progressPrinter{endpoint: 1}
+	sslClient.Progress = func(host *ssllabs.Host) error {
+		event := newProgressEvent(host, time.Now())
+		// Emit a structured progress event for machine consumers
+		if progress != nil {
+			if err := writeProgressEvent(progress, event); err != nil {
+				return fmt.Errorf("failed to write progress event: %v", err)
+			}
+		}
+		// Update the progress file for external orchestrators
+		if *progressFile != "" {
+			if err := writeProgressFile(*progressFile, event); err != nil {
+				return err
+			}
+		}
+		printer.print(host)
+		return nil
+	}
+	// Probe the domain directly while SSL Labs assesses it
+	var directDone <-chan *directProbe
+	if *hybrid {
+		directDone = startDirectProbe(*domain, 10*time.Second)
+	}
+	logf("Checking SSL/TLS for domain: %s\n", *domain)
+	if *maxResultAge > 0 {
+		// Reuse a fresh cached result, a new assessment is started otherwise
+		logf("Looking for a cached result newer than %s ....\n", *maxResultAge)
+	} else {
+		logln("Starting Assessment ....")
+	}
+	// Check the API capacity, start or reuse the assessment and wait for it to complete
+	result, err := ssllabs.Scan(ctx, *domain, ssllabs.Options{
+		Client:    sslClient,
+		Publish:   *publish,
+		FromCache: *maxResultAge > 0,
+		MaxAge:    *maxResultAge,
+	})
+	if printer.started {
+		logln()
+	}
+	if errors.Is(err, ssllabs.ErrMaxAssessments) {
+		logln("Maximum number of concurrent assessments reached. Please try again later.")
+		exitUpstreamFailure(err, *softFail)
+	}
+	if err != nil {
+		logf("Error: %v\n", err)
+		exitUpstreamFailure(err, *softFail)
+	}
+	host := result.Host
+	// Display API status information
+	logf("Criteria Version: %s\n", result.Info.CriteriaVersion)
+	logf("Concurrent assessments allowed: %d\n", result.Info.MaxAssessments)
+	// A result tested before this run started came from the cache
+	if *maxResultAge > 0 && time.UnixMilli(host.TestTime).Before(started) {
+		logf("Using cached result for %s\n", host.Host)
+	}
+	// Check the homepage for mixed content if requested, the findings are part of the result
+	var insecure []string
+	if *mixedContent {
+		urls, err := findMixedContent(*domain)
+		if err != nil {
+			logf("Error checking mixed content: %v\n", err)
+		} else if len(urls) == 0 {
+			logln("Mixed content: none found on the homepage")
+		} else {
+			logf("Mixed content: %d insecure subresources on the homepage\n", len(urls))
+			insecure = urls
+		}
+	}
+	// Merge the direct probe, which has usually finished long before the assessment
+	var direct *directProbe
+	if directDone != nil {
+		direct = <-directDone
+	}
+	scanResult := newResultDocument(host, scanNotes, insecure).withDirect(direct)
 	// Display the final results
-	displayResults(host)
+	if err := writeResults(*resultsFile, scanResult, *output, scanPolicy); err != nil {
+		logf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	// Write the evidence bundle if requested
+	if *evidence != "" {
+		if err := writeEvidenceBundle(*evidence, scanResult, started, time.Now()); err != nil {
+			logf("Error writing evidence bundle: %v\n", err)
+			os.Exit(1)
+		}
+		logf("Evidence bundle written to %s\n", *evidence)
+	}
+	// Write the scan manifest if requested
+	if *manifest != "" {
+		if err := writeManifest(*manifest, buildManifest(scanResult, started, time.Now())); err != nil {
+			logf("Error writing manifest: %v\n", err)
+			os.Exit(1)
+		}
+		logf("Manifest written to %s\n", *manifest)
+	}
+	// Notify the webhook if requested
+	if *webhook != "" {
+		event, err := newScanCompletedEvent(scanResult, time.Now())
+		if err == nil {
+			err = sendWebhook(*webhook, event)
+		}
+		if err != nil {
+			logf("Error sending webhook: %v\n", err)
+			os.Exit(1)
+		}
+		logf("Webhook sent to %s\n", *webhook)
+	}
+	// Escalate expiring certificates to the webhook of the checkpoint they reached
+	for _, notification := range expiryAlerts.expiryNotifications(host, time.Now()) {
+		event, err := newCloudEvent(cloudEventCertExpiring, host.Host, notification.alert, time.Now())
+		if err == nil {
+			err = sendWebhook(notification.url, event)
+		}
+		if err != nil {
+			logf("Error sending expiry alert: %v\n", err)
+			os.Exit(1)
+		}
+		logf("Expiry alert for %s (%d days left, %d-day checkpoint) sent to %s\n", notification.alert.Subject, notification.alert.DaysLeft, notification.alert.Checkpoint, notification.url)
+	}
+	// Evaluate the policy against the results
+	for _, endpoint := range host.Endpoints {
+		if scanPolicy.isBestEffort(endpoint) {
+			logf("Ignoring best-effort endpoint %s in policy evaluation\n", endpoint.IpAddress)
+		}
+	}
+	violations := scanPolicy.violations(scanResult, time.Now())
+	// Report what changed since the baseline, and only fail on it if requested
+	if baseline != nil {
+		regressions := scanPolicy.regressions(baseline, scanResult, time.Now())
+		for _, regression := range regressions {
+			logf("Regression: %s\n", regression)
+		}
+		if len(regressions) == 0 {
+			logln("No regressions since the baseline")
+		}
+		if *onlyRegressions {
+			if len(violations) > 0 && len(regressions) == 0 {
+				logf("Ignoring %d policy violations present in the baseline\n", len(violations))
+			}
+			violations = regressions
+		}
+	}
+	if len(violations) > 0 {
+		for _, violation := range violations {
+			logf("Policy violation: %s\n", violation)
+		}
+		os.Exit(exitPolicyViolation)
+	}
 }
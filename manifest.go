@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// version of the tool, overridable at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+// Structs to describe the exact inputs and engine versions behind a scan
+type Manifest struct {
+	ToolVersion     string            `json:"toolVersion"`
+	ConfigHash      string            `json:"configHash"`
+	Flags           map[string]string `json:"flags"`
+	Domains         []string          `json:"domains"`
+	Port            int               `json:"port"`
+	Protocol        string            `json:"protocol"`
+	EngineVersion   string            `json:"engineVersion"`
+	CriteriaVersion string            `json:"criteriaVersion"`
+	StartedAt       string            `json:"startedAt"`
+	FinishedAt      string            `json:"finishedAt"`
+	AssessmentStart string            `json:"assessmentStart"`
+	AssessmentTest  string            `json:"assessmentTest"`
+	ResultHash      string            `json:"resultHash"`
+	ContentHash     string            `json:"contentHash"`
+	Notes           []string          `json:"notes,omitempty"`
+}
+
+// secretFlags lists the flags whose values carry credentials, such as hook tokens embedded
+// in webhook URLs or the ownership verification token. Manifests are handed off with the
+// evidence bundle, so they only record whether these flags were set.
+var secretFlags = map[string]bool{
+	"webhook":          true,
+	"expiry-alert":     true,
+	"verify-ownership": true,
+}
+
+// Value recorded in manifests instead of the value of a secret flag
+const redactedFlagValue = "[REDACTED]"
+
+// flagValue returns the value of a flag as recorded in manifests, with secrets redacted
+func flagValue(f *flag.Flag) string {
+	value := f.Value.String()
+	if secretFlags[f.Name] && value != "" {
+		return redactedFlagValue
+	}
+	return value
+}
+
+// flagValues returns the current value of every command-line flag
+func flagValues() map[string]string {
+	values := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		values[f.Name] = flagValue(f)
+	})
+	return values
+}
+
+// configHash returns a SHA-256 hash of the command-line flags used for the scan. Secrets
+// are redacted first so the hash cannot be used to confirm a guessed token.
+func configHash() string {
+	h := sha256.New()
+	// VisitAll walks flags in lexicographical order, so the hash is stable
+	flag.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(h, "%s=%s\n", f.Name, flagValue(f))
+	})
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// buildManifest describes the configuration and engine that produced the given result
+func buildManifest(result *ResultDocument, started, finished time.Time) Manifest {
+	host := result.Host
+	sum := sha256.Sum256(host.Raw)
+	return Manifest{
+		ToolVersion:     version,
+		ConfigHash:      configHash(),
+		Flags:           flagValues(),
+		Domains:         []string{host.Host},
+		Port:            host.Port,
+		Protocol:        host.Protocol,
+		EngineVersion:   host.EngineVersion,
+		CriteriaVersion: host.CriteriaVersion,
+		StartedAt:       started.UTC().Format(time.RFC3339),
+		FinishedAt:      finished.UTC().Format(time.RFC3339),
+		AssessmentStart: time.UnixMilli(host.StartTime).UTC().Format(time.RFC3339),
+		AssessmentTest:  time.UnixMilli(host.TestTime).UTC().Format(time.RFC3339),
+		ResultHash:      hex.EncodeToString(sum[:]),
+		ContentHash:     result.ContentHash,
+		Notes:           result.Notes,
+	}
+}
+
+// writeManifest writes the scan manifest as JSON to the given file
+func writeManifest(path string, manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestManifestRedactsSecretFlags(t *testing.T) {
+	webhook := flag.String("webhook", "", "")
+	flag.String("verify-ownership", "", "")
+	flag.String("min-grade", "", "")
+	defer func() { *webhook = "" }()
+	hashes := make(map[string]bool)
+	for _, url := range []string{"https://hooks.example/T000/B000/secret1", "https://hooks.example/T000/B000/secret2"} {
+		flag.Set("webhook", url)
+		flag.Set("verify-ownership", "token")
+		flag.Set("min-grade", "A")
+		values := flagValues()
+		if values["webhook"] != redactedFlagValue || values["verify-ownership"] != redactedFlagValue {
+			t.Errorf("webhook = %q, verify-ownership = %q, want both redacted", values["webhook"], values["verify-ownership"])
+		}
+		if values["min-grade"] != "A" {
+			t.Errorf("min-grade = %q, want A", values["min-grade"])
+		}
+		for name, value := range values {
+			if strings.Contains(value, "secret") || strings.Contains(value, "token") {
+				t.Errorf("flag %s records %q", name, value)
+			}
+		}
+		hashes[configHash()] = true
+	}
+	if len(hashes) != 1 {
+		t.Error("configHash depends on the webhook URL, want it computed from redacted values")
+	}
+	// Unset secret flags stay empty so the manifest shows they were not used
+	flag.Set("webhook", "")
+	if value := flagValues()["webhook"]; value != "" {
+		t.Errorf("unset webhook = %q, want empty", value)
+	}
+}
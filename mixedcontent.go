@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Maximum size of the homepage read when looking for mixed content
+const maxHomepageSize = 5 << 20
+
+// subresourceTags lists the tags loading the resource named by their URL attributes
+var subresourceTags = map[string]bool{
+	"script": true, "img": true, "iframe": true, "link": true, "source": true,
+	"audio": true, "video": true, "embed": true, "object": true, "track": true,
+}
+
+// findMixedContent fetches the homepage of the domain over HTTPS and returns the http://
+// subresources it loads, which browsers block or flag as insecure
+func findMixedContent(domain string) ([]string, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get("https://" + domain + "/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch homepage: %v", err)
+	}
+	defer resp.Body.Close()
+	// A redirect to plain HTTP makes every resource insecure
+	if resp.Request.URL.Scheme != "https" {
+		return []string{fmt.Sprintf("homepage redirects to %s", resp.Request.URL)}, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHomepageSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read homepage: %v", err)
+	}
+	return mixedContentURLs(string(body)), nil
+}
+
+// mixedContentURLs returns the http:// URLs loaded as subresources by an HTML page.
+// Links are only counted when they load a resource such as a stylesheet or icon. The page
+// is tokenized like a browser does, so quoted attribute values, comments and script
+// contents are never mistaken for attributes or tags.
+func mixedContentURLs(page string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	z := html.NewTokenizer(strings.NewReader(page))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return urls
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			if !subresourceTags[token.Data] {
+				continue
+			}
+			// Browsers use the first occurrence of a repeated attribute
+			attrs := make(map[string]string)
+			for _, attr := range token.Attr {
+				if _, ok := attrs[attr.Key]; !ok {
+					attrs[attr.Key] = strings.TrimSpace(attr.Val)
+				}
+			}
+			for _, name := range []string{"src", "href", "data"} {
+				url := attrs[name]
+				if !strings.HasPrefix(strings.ToLower(url), "http://") || seen[url] {
+					continue
+				}
+				rel := strings.ToLower(attrs["rel"])
+				if token.Data == "link" && !strings.Contains(rel, "stylesheet") && !strings.Contains(rel, "icon") {
+					continue
+				}
+				seen[url] = true
+				urls = append(urls, url)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+func TestMixedContentURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want []string
+	}{
+		{"insecure image", `<img src="http://cdn.example/a.png">`, []string{"http://cdn.example/a.png"}},
+		{"secure image", `<img src="https://cdn.example/a.png">`, nil},
+		{"unquoted and uppercase", `<IMG SRC=http://cdn.example/a.png>`, []string{"http://cdn.example/a.png"}},
+		{"data attributes are not URLs", `<img data-src="http://cdn.example/lazy.png" src="https://cdn.example/a.png">`, nil},
+		{"first occurrence wins", `<img src="https://cdn.example/a.png" src="http://cdn.example/b.png">`, nil},
+		{"attribute inside a quoted value", `<img alt='x src=http://evil.example/a.png' src="https://ok.example/a.png">`, nil},
+		{"quoted value hiding the real attribute", `<img alt='x src=https://ok.example/a.png' src="http://cdn.example/a.png">`, []string{"http://cdn.example/a.png"}},
+		{"tag inside a comment", `<!-- <script src="http://cdn.example/a.js"></script> -->`, nil},
+		{"tag inside a script", `<script>document.write('<img src="http://cdn.example/a.png">')</script>`, nil},
+		{"plain link", `<link rel="canonical" href="http://www.example/">`, nil},
+		{"stylesheet link", `<link rel="stylesheet" href="http://cdn.example/a.css">`, []string{"http://cdn.example/a.css"}},
+		{"duplicates", `<img src="http://cdn.example/a.png"><img src="http://cdn.example/a.png">`, []string{"http://cdn.example/a.png"}},
+	}
+	for _, tt := range tests {
+		if got := mixedContentURLs(tt.page); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: mixedContentURLs = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMixedContentViolations(t *testing.T) {
+	p, err := newPolicy("", gradeModifiersStrict, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := &ssllabs.Host{Host: "example.com", Status: "READY"}
+	result := newResultDocument(host, nil, []string{"http://cdn.example/a.png"})
+	if violations := p.violations(result, time.Now()); len(violations) != 1 {
+		t.Errorf("violations = %q, want the insecure subresource", violations)
+	}
+	if regressions := p.regressions(result, result, time.Now()); len(regressions) != 0 {
+		t.Errorf("regressions against the same result = %q, want none", regressions)
+	}
+	baseline := newResultDocument(host, nil, nil)
+	if regressions := p.regressions(baseline, result, time.Now()); len(regressions) != 1 {
+		t.Errorf("regressions = %q, want the new insecure subresource", regressions)
+	}
+}
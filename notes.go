@@ -0,0 +1,21 @@
+package main
+
+import "strings"
+
+// Structs to collect repeated -note flags
+type noteList []string
+
+// String returns the notes joined by semicolons
+func (n *noteList) String() string {
+	return strings.Join(*n, "; ")
+}
+
+// Set adds a note
+func (n *noteList) Set(value string) error {
+	*n = append(*n, value)
+	return nil
+}
+
+// scanNotes holds the free-text notes attached to the scan, shown in reports and recorded
+// in manifests and evidence bundles
+var scanNotes noteList
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// legacyVersions returns the protocols older than TLS 1.2 a probed service accepts, whether
+// negotiated or merely accepted
+func legacyVersions(result probeResult) map[uint16]bool {
+	versions := make(map[uint16]bool)
+	for _, version := range result.LegacyVersions {
+		versions[version] = true
+	}
+	if result.Version < tls.VersionTLS12 {
+		versions[result.Version] = true
+	}
+	return versions
+}
+
+// originFindings compares the TLS settings of an origin server with those of the CDN edge
+// in front of it. The origin accepting a protocol the edge refuses is critical: clients
+// reaching the origin directly bypass the edge and negotiate it.
+func originFindings(edge, origin probeResult) (critical, warnings []string) {
+	if origin.Err != nil {
+		// An origin only reachable from the edge is the safest setup
+		return nil, []string{fmt.Sprintf("origin not reachable directly, it may only accept the edge: %v", origin.Err)}
+	}
+	edgeVersions, originVersions := legacyVersions(edge), legacyVersions(origin)
+	for _, version := range []uint16{tls.VersionTLS10, tls.VersionTLS11} {
+		if originVersions[version] && !edgeVersions[version] {
+			critical = append(critical, fmt.Sprintf("origin accepts %s, the edge refuses it", tls.VersionName(version)))
+		}
+	}
+	if origin.Version < edge.Version {
+		warnings = append(warnings, fmt.Sprintf("origin negotiates %s, the edge %s", tls.VersionName(origin.Version), tls.VersionName(edge.Version)))
+	}
+	return critical, warnings
+}
+
+// runCompareOrigin implements the compare-origin subcommand: it probes a CDN edge host and
+// its origin server directly, under the name of the edge, and reports where the origin is
+// weaker than the edge. It returns the process exit code.
+func runCompareOrigin(args []string) int {
+	fs := flag.NewFlagSet("compare-origin", flag.ExitOnError)
+	port := fs.Int("port", 443, "Port of the edge and the origin")
+	caFile := fs.String("ca-file", "", "PEM bundle of CA certificates to verify against instead of the system roots")
+	timeout := fs.Duration("timeout", 5*time.Second, "Timeout for each connection")
+	resultsFile := fs.String("results-file", "", "Write the results to this file instead of stdout")
+	logFile := fs.String("log-file", "", "Append progress and status messages to this file instead of stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ssl-checker compare-origin [flags] edge.example.com origin")
+		fmt.Fprintln(fs.Output(), "The origin is an IP address or host name, probed with the edge host name as server name")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 1
+	}
+	// Redirect the logs if requested
+	closeLog, err := redirectLogs(*logFile)
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	defer closeLog()
+	p := &prober{timeout: *timeout, expiryWarning: defaultProbeExpiryWarning}
+	if *caFile != "" {
+		if p.roots, err = loadCAFile(*caFile); err != nil {
+			logf("Error: %v\n", err)
+			return 1
+		}
+	}
+	edgeHost := fs.Arg(0)
+	logf("Probing edge %s and origin %s ....\n", edgeHost, fs.Arg(1))
+	edge := p.probe(probeTarget{Host: edgeHost, Port: *port, Role: "edge"})
+	if edge.Err != nil {
+		logf("Error: edge %s: %v\n", edge.Target, edge.Err)
+		return 1
+	}
+	origin := p.probe(probeTarget{Host: fs.Arg(1), Port: *port, Role: "origin", ServerName: edgeHost})
+	now := time.Now()
+	edgeCritical, edgeWarnings := p.findings(edge, now)
+	var originCritical, originWarnings []string
+	if origin.Err == nil {
+		originCritical, originWarnings = p.findings(origin, now)
+	}
+	critical, warnings := originFindings(edge, origin)
+	originCritical = append(originCritical, critical...)
+	originWarnings = append(originWarnings, warnings...)
+	err = writeResultsWith(*resultsFile, func(w io.Writer) error {
+		displayProbeResult(w, edge, edgeCritical, edgeWarnings)
+		displayProbeResult(w, origin, originCritical, originWarnings)
+		return nil
+	})
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	if len(edgeCritical) > 0 || len(originCritical) > 0 {
+		return exitPolicyViolation
+	}
+	return 0
+}
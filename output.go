@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+// Supported formats for the assessment results
+const (
+	outputText    = "text"
+	outputJSON    = "json"
+	outputZabbix  = "zabbix"
+	outputCheckmk = "checkmk"
+)
+
+// logOutput receives progress and status messages, keeping stdout for the results
+var logOutput io.Writer = os.Stderr
+
+// logf writes a formatted progress or status message
+func logf(format string, args ...any) {
+	fmt.Fprintf(logOutput, format, args...)
+}
+
+// logln writes a progress or status message followed by a newline
+func logln(args ...any) {
+	fmt.Fprintln(logOutput, args...)
+}
+
+// redirectLogs appends progress and status messages to the file at path instead of
+// stderr, when a path is given. The returned function closes the file and restores stderr.
+func redirectLogs(path string) (func(), error) {
+	if path == "" {
+		return func() {}, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %v", err)
+	}
+	logOutput = f
+	return func() {
+		logOutput = os.Stderr
+		f.Close()
+	}, nil
+}
+
+// validateOutput checks the output format flag
+func validateOutput(format string) error {
+	switch format {
+	case outputText, outputJSON, outputZabbix, outputCheckmk:
+		return nil
+	default:
+		return fmt.Errorf("invalid output %q: expected %s, %s, %s or %s", format, outputText, outputJSON, outputZabbix, outputCheckmk)
+	}
+}
+
+// renderResults writes the assessment results in the given format
+func renderResults(w io.Writer, result *ResultDocument, format string, p *policy) error {
+	switch format {
+	case outputJSON:
+		return writeJSON(w, result)
+	case outputZabbix:
+		return writeJSON(w, zabbixSenderData(result))
+	case outputCheckmk:
+		writeCheckmk(w, result, p)
+		return nil
+	default:
+		displayResults(w, result)
+		return nil
+	}
+}
+
+// writeResults renders the results to the given file, or to stdout if path is empty.
+// The content hash of JSON results is logged so downstream storage can deduplicate them.
+func writeResults(path string, result *ResultDocument, format string, p *policy) error {
+	err := writeResultsWith(path, func(w io.Writer) error {
+		return renderResults(w, result, format, p)
+	})
+	if err == nil && format == outputJSON {
+		logf("Results content hash: sha256:%s\n", result.ContentHash)
+	}
+	return err
+}
+
+// writeResultsWith calls render with the given file, or with stdout if path is empty
+func writeResultsWith(path string, render func(w io.Writer) error) error {
+	if path == "" {
+		return render(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create results file: %v", err)
+	}
+	if err := render(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write results file: %v", err)
+	}
+	return nil
+}
+
+// writeJSON writes a value as indented JSON with the keys of every object sorted, so equal
+// values always encode to the same bytes
+func writeJSON(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode results: %v", err)
+	}
+	// Objects decoded into generic values become maps, which encode with sorted keys;
+	// numbers are kept as written so they do not lose precision on the way
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var sorted any
+	if err := dec.Decode(&sorted); err != nil {
+		return fmt.Errorf("failed to encode results: %v", err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(sorted); err != nil {
+		return fmt.Errorf("failed to encode results: %v", err)
+	}
+	return nil
+}
+
+// Structs to describe a zabbix_sender JSON request
+type ZabbixSenderRequest struct {
+	Request string             `json:"request"`
+	Data    []ZabbixSenderItem `json:"data"`
+}
+
+// Structs to describe a single item value sent to Zabbix
+type ZabbixSenderItem struct {
+	Host  string `json:"host"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// zabbixSenderData builds a zabbix_sender request with a low-level discovery item for the
+// endpoints, the scan notes and the mixed content count followed by the grade, risk score,
+// warnings and expiry of each endpoint
+func zabbixSenderData(result *ResultDocument) ZabbixSenderRequest {
+	host := result.Host
+	var discovery []map[string]string
+	for _, endpoint := range host.Endpoints {
+		discovery = append(discovery, map[string]string{"{#IPADDRESS}": endpoint.IpAddress})
+	}
+	lld, _ := json.Marshal(discovery)
+	request := ZabbixSenderRequest{Request: "sender data"}
+	add := func(key, value string) {
+		request.Data = append(request.Data, ZabbixSenderItem{Host: host.Host, Key: key, Value: value})
+	}
+	add("ssl.status", host.Status)
+	add("ssl.discovery", string(lld))
+	add("ssl.inconsistencies", fmt.Sprint(len(endpointInconsistencies(host))))
+	add("ssl.notes", strings.Join(result.Notes, "; "))
+	add("ssl.mixedcontent", fmt.Sprint(len(result.MixedContent)))
+	now := time.Now()
+	for _, endpoint := range host.Endpoints {
+		add(fmt.Sprintf("ssl.grade[%s]", endpoint.IpAddress), endpoint.Grade)
+		add(fmt.Sprintf("ssl.risk[%s]", endpoint.IpAddress), fmt.Sprint(riskScore(endpoint, now)))
+		add(fmt.Sprintf("ssl.warnings[%s]", endpoint.IpAddress), fmt.Sprint(len(endpointWarnings(endpoint))))
+		add(fmt.Sprintf("ssl.expired[%s]", endpoint.IpAddress), fmt.Sprint(certExpired(endpoint.Details.Cert, now)))
+	}
+	return request
+}
+
+// Checkmk local check states
+const (
+	checkmkOK = iota
+	checkmkWarn
+	checkmkCrit
+	checkmkUnknown
+)
+
+// checkmkState maps an endpoint to a Checkmk state: an expired certificate, a grade below
+// the minimum grade or graded C or worse is critical, B or warnings is a warning, and a
+// missing grade is unknown. Best-effort endpoints are at most a warning.
+func checkmkState(endpoint ssllabs.Endpoint, p *policy, now time.Time) int {
+	rank := gradeRank(endpoint.Grade, p.modifiers)
+	state := checkmkOK
+	switch {
+	case certExpired(endpoint.Details.Cert, now):
+		state = checkmkCrit
+	case rank < 0:
+		return checkmkUnknown
+	case p.minGrade != "" && rank < gradeRank(p.minGrade, p.modifiers):
+		state = checkmkCrit
+	case rank < gradeRank("B", p.modifiers):
+		state = checkmkCrit
+	case rank < gradeRank("A", p.modifiers) || len(endpointWarnings(endpoint)) > 0:
+		state = checkmkWarn
+	}
+	if state == checkmkCrit && p.isBestEffort(endpoint) {
+		return checkmkWarn
+	}
+	return state
+}
+
+// writeCheckmk writes one Checkmk local check line per endpoint, with the scan notes
+// appended to the host-level line, and a critical line for mixed content if any was found
+func writeCheckmk(w io.Writer, result *ResultDocument, p *policy) {
+	host := result.Host
+	var noted string
+	if len(result.Notes) > 0 {
+		noted = " (Notes: " + strings.Join(result.Notes, "; ") + ")"
+	}
+	// A failed assessment is reported as a single critical service
+	if host.Status != "READY" {
+		fmt.Fprintf(w, "%d \"SSL %s\" - Assessment %s: %s%s\n", checkmkCrit, host.Host, strings.ToLower(host.Status), host.StatusMessage, noted)
+		return
+	}
+	now := time.Now()
+	// Endpoints disagreeing with each other are reported as a host-level warning
+	if findings := endpointInconsistencies(host); len(findings) > 0 {
+		fmt.Fprintf(w, "%d \"SSL %s consistency\" - Inconsistent endpoints: %s%s\n", checkmkWarn, host.Host, strings.Join(findings, "; "), noted)
+	} else {
+		fmt.Fprintf(w, "%d \"SSL %s consistency\" - All endpoints consistent%s\n", checkmkOK, host.Host, noted)
+	}
+	// Insecure subresources on the homepage are reported as a host-level critical
+	if len(result.MixedContent) > 0 {
+		fmt.Fprintf(w, "%d \"SSL %s mixed content\" - %d insecure subresources: %s\n", checkmkCrit, host.Host, len(result.MixedContent), strings.Join(result.MixedContent, ", "))
+	}
+	for _, endpoint := range host.Endpoints {
+		grade := endpoint.Grade
+		if grade == "" {
+			grade = "none"
+		}
+		summary := fmt.Sprintf("Grade %s", grade)
+		if finding := expiredCertFinding(host, endpoint, now); finding != "" {
+			summary += ", " + finding
+		}
+		if warnings := endpointWarnings(endpoint); len(warnings) > 0 {
+			summary += ", " + strings.Join(warnings, ", ")
+		}
+		fmt.Fprintf(w, "%d \"SSL %s %s\" risk=%d;;;0;100 %s\n", checkmkState(endpoint, p, now), host.Host, endpoint.IpAddress, riskScore(endpoint, now), summary)
+	}
+}
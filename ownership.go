@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Prefix of the DNS TXT record holding the ownership verification token of a domain
+const ownershipTXTPrefix = "ssl-checker-verification="
+
+// Path of the well-known file holding the ownership verification token of a domain
+const ownershipWellKnownPath = "/.well-known/ssl-checker-verification"
+
+// verifyOwnership checks that the domain publishes the given token, either in a DNS TXT
+// record "ssl-checker-verification=<token>" or in its well-known verification file served
+// over HTTPS, so results are only published for domains under the operator's control
+func verifyOwnership(ctx context.Context, domain, token string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	// Look for the DNS TXT record first
+	records, dnsErr := net.DefaultResolver.LookupTXT(ctx, domain)
+	for _, record := range records {
+		if record == ownershipTXTPrefix+token {
+			return nil
+		}
+	}
+	// Fall back to the well-known file
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+domain+ownershipWellKnownPath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create verification request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err == nil {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode == http.StatusOK && strings.TrimSpace(string(body)) == token {
+			return nil
+		}
+	}
+	if dnsErr != nil {
+		return fmt.Errorf("ownership of %s not verified: no %s TXT record (%v) and no token at https://%s%s", domain, ownershipTXTPrefix, dnsErr, domain, ownershipWellKnownPath)
+	}
+	return fmt.Errorf("ownership of %s not verified: no %s TXT record and no token at https://%s%s", domain, ownershipTXTPrefix, domain, ownershipWellKnownPath)
+}
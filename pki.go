@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of PKI service endpoints checked by the pki-check subcommand
+const (
+	pkiEnrollment = "enroll"
+	pkiOCSP       = "ocsp"
+	pkiCRL        = "crl"
+)
+
+// pkiRoles describes each kind of PKI endpoint
+var pkiRoles = map[string]string{
+	pkiEnrollment: "CA web enrollment",
+	pkiOCSP:       "OCSP responder",
+	pkiCRL:        "CRL distribution point",
+}
+
+// Delay before the next CRL update from which a published CRL is flagged as stale
+const crlNextUpdateWarning = expiryDay
+
+// Maximum size of a CRL downloaded from a distribution point
+const maxCRLSize = 64 << 20
+
+// Structs to describe a PKI service endpoint to check
+type pkiEndpoint struct {
+	Kind string
+	URL  *url.URL
+}
+
+// Structs to describe the outcome of a PKI endpoint check
+type pkiResult struct {
+	Endpoint pkiEndpoint
+	// TLS is set for HTTPS endpoints
+	TLS        *probeResult
+	StatusCode int
+	CRL        *x509.RevocationList
+	Err        error
+}
+
+// parsePKIEndpoints reads "kind URL" entries, e.g. "crl http://pki.example.com/root.crl"
+func parsePKIEndpoints(entries []string) ([]pkiEndpoint, error) {
+	var endpoints []pkiEndpoint
+	for _, entry := range entries {
+		fields := strings.Fields(entry)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid PKI endpoint %q: expected kind and URL", entry)
+		}
+		if _, ok := pkiRoles[fields[0]]; !ok {
+			return nil, fmt.Errorf("invalid PKI endpoint kind %q: expected %s, %s or %s", fields[0], pkiEnrollment, pkiOCSP, pkiCRL)
+		}
+		u, err := url.Parse(fields[1])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid PKI endpoint URL %q", fields[1])
+		}
+		endpoints = append(endpoints, pkiEndpoint{Kind: fields[0], URL: u})
+	}
+	return endpoints, nil
+}
+
+// checkPKIEndpoint probes the TLS service of HTTPS endpoints and checks the endpoint
+// answers the way its kind of service should
+func checkPKIEndpoint(p *prober, endpoint pkiEndpoint) pkiResult {
+	result := pkiResult{Endpoint: endpoint}
+	if endpoint.URL.Scheme == "https" {
+		port := 443
+		if endpoint.URL.Port() != "" {
+			port, _ = strconv.Atoi(endpoint.URL.Port())
+		}
+		probed := p.probe(probeTarget{Host: endpoint.URL.Hostname(), Port: port, Role: pkiRoles[endpoint.Kind]})
+		result.TLS = &probed
+		if probed.Err != nil {
+			return result
+		}
+	}
+	// Certificates were checked by the probe, the HTTP check only looks at the service
+	client := &http.Client{
+		Timeout:   p.timeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+	var resp *http.Response
+	var err error
+	if endpoint.Kind == pkiOCSP {
+		// An empty request must still be answered with an OCSP malformedRequest response
+		resp, err = client.Post(endpoint.URL.String(), "application/ocsp-request", bytes.NewReader(nil))
+	} else {
+		resp, err = client.Get(endpoint.URL.String())
+	}
+	if err != nil {
+		result.Err = fmt.Errorf("request failed: %v", err)
+		return result
+	}
+	defer resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+	switch endpoint.Kind {
+	case pkiOCSP:
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if mediaType != "application/ocsp-response" {
+			result.Err = fmt.Errorf("unexpected content type %q, expected application/ocsp-response", resp.Header.Get("Content-Type"))
+		}
+	case pkiCRL:
+		if resp.StatusCode != http.StatusOK {
+			result.Err = fmt.Errorf("unexpected status %s", resp.Status)
+			return result
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxCRLSize))
+		if err != nil {
+			result.Err = fmt.Errorf("failed to download CRL: %v", err)
+			return result
+		}
+		if result.CRL, err = parseCRL(data); err != nil {
+			result.Err = err
+		}
+	case pkiEnrollment:
+		// Enrollment pages commonly require Windows authentication, which still shows the service is up
+		if resp.StatusCode >= 400 && resp.StatusCode != http.StatusUnauthorized {
+			result.Err = fmt.Errorf("unexpected status %s", resp.Status)
+		}
+	}
+	return result
+}
+
+// parseCRL parses a DER or PEM encoded certificate revocation list
+func parseCRL(data []byte) (*x509.RevocationList, error) {
+	if block, _ := pem.Decode(data); block != nil && block.Type == "X509 CRL" {
+		data = block.Bytes
+	}
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CRL: %v", err)
+	}
+	return crl, nil
+}
+
+// pkiFindings returns the problems found on a PKI endpoint. Unreachable or misbehaving
+// services, certificate problems and expired CRLs are critical.
+func pkiFindings(p *prober, result pkiResult, now time.Time) (critical, warnings []string) {
+	if result.TLS != nil {
+		critical, warnings = p.findings(*result.TLS, now)
+	}
+	if result.Err != nil {
+		critical = append(critical, result.Err.Error())
+	}
+	if result.CRL != nil && !result.CRL.NextUpdate.IsZero() {
+		switch {
+		case expired(result.CRL.NextUpdate, now):
+			critical = append(critical, fmt.Sprintf("CRL expired %s", relativeTime(result.CRL.NextUpdate, now)))
+		case expiresWithin(result.CRL.NextUpdate, now, crlNextUpdateWarning):
+			warnings = append(warnings, fmt.Sprintf("CRL expires %s", relativeTime(result.CRL.NextUpdate, now)))
+		}
+	}
+	return critical, warnings
+}
+
+// displayPKIResult prints the outcome of a PKI endpoint check and its findings
+func displayPKIResult(w io.Writer, result pkiResult, critical, warnings []string) {
+	fmt.Fprintf(w, "Endpoint: %s (%s)\n", result.Endpoint.URL, pkiRoles[result.Endpoint.Kind])
+	if result.TLS != nil && result.TLS.Err == nil {
+		displayProbeDetails(w, *result.TLS)
+	}
+	if result.StatusCode != 0 {
+		fmt.Fprintf(w, "  HTTP Status: %d\n", result.StatusCode)
+	}
+	if result.CRL != nil {
+		fmt.Fprintf(w, "  CRL Issuer: %s\n", result.CRL.Issuer.CommonName)
+		fmt.Fprintf(w, "  CRL Updated: %s\n", formatTime(result.CRL.ThisUpdate))
+		fmt.Fprintf(w, "  CRL Next Update: %s\n", formatTime(result.CRL.NextUpdate))
+		fmt.Fprintf(w, "  Revoked Certificates: %d\n", len(result.CRL.RevokedCertificateEntries))
+	}
+	displayFindings(w, critical, warnings)
+	fmt.Fprintln(w)
+}
+
+// runPKICheck implements the pki-check subcommand: it checks the TLS certificates and
+// responses of CA web enrollment, OCSP and CRL endpoints. It returns the process exit code.
+func runPKICheck(args []string) int {
+	fs := flag.NewFlagSet("pki-check", flag.ExitOnError)
+	caFile := fs.String("ca-file", "", "PEM bundle of CA certificates to verify against instead of the system roots")
+	timeout := fs.Duration("timeout", 10*time.Second, "Timeout for each connection and request")
+	resultsFile := fs.String("results-file", "", "Write the results to this file instead of stdout")
+	logFile := fs.String("log-file", "", "Append progress and status messages to this file instead of stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ssl-checker pki-check [flags] endpoints.txt")
+		fmt.Fprintln(fs.Output(), "Each line holds a kind (enroll, ocsp or crl) and a URL, e.g. \"crl http://pki.example.com/root.crl\"")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	// Redirect the logs if requested
+	closeLog, err := redirectLogs(*logFile)
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	defer closeLog()
+	entries, err := readDomainList(fs.Arg(0))
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	endpoints, err := parsePKIEndpoints(entries)
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	p := &prober{timeout: *timeout, expiryWarning: defaultProbeExpiryWarning}
+	if *caFile != "" {
+		if p.roots, err = loadCAFile(*caFile); err != nil {
+			logf("Error: %v\n", err)
+			return 1
+		}
+	}
+	now := time.Now()
+	failed := false
+	err = writeResultsWith(*resultsFile, func(w io.Writer) error {
+		for _, endpoint := range endpoints {
+			result := checkPKIEndpoint(p, endpoint)
+			critical, warnings := pkiFindings(p, result, now)
+			displayPKIResult(w, result, critical, warnings)
+			failed = failed || len(critical) > 0
+		}
+		return nil
+	})
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	if failed {
+		return exitPolicyViolation
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+// Structs to describe the estimated schedule of a run over a domain list
+type runPlan struct {
+	Domains    int
+	Slots      int
+	Batches    int
+	Assessment time.Duration
+	CoolOff    time.Duration
+	// CoolOffs is the total time spent in cool-off between new assessments
+	CoolOffs time.Duration
+	Duration time.Duration
+}
+
+// planRun simulates running one assessment per domain the way prefetch does: a new
+// assessment starts once a slot is free and the cool-off since the previous start has
+// passed, and each assessment holds its slot for the given duration
+func planRun(domains, slots int, coolOff, assessment time.Duration) runPlan {
+	plan := runPlan{Domains: domains, Slots: slots, Assessment: assessment, CoolOff: coolOff}
+	if domains == 0 {
+		return plan
+	}
+	plan.Batches = (domains + slots - 1) / slots
+	plan.CoolOffs = time.Duration(domains-1) * coolOff
+	// freeAt holds the time each slot becomes available again
+	freeAt := make([]time.Duration, slots)
+	start := time.Duration(0)
+	for i := 0; i < domains; i++ {
+		slot := 0
+		for j := range freeAt {
+			if freeAt[j] < freeAt[slot] {
+				slot = j
+			}
+		}
+		if i > 0 {
+			start += coolOff
+		}
+		start = max(start, freeAt[slot])
+		freeAt[slot] = start + assessment
+		plan.Duration = max(plan.Duration, freeAt[slot])
+	}
+	return plan
+}
+
+// displayPlan prints the estimated schedule to the given writer
+func displayPlan(w io.Writer, info *ssllabs.Info, plan runPlan, now time.Time) {
+	fmt.Fprintf(w, "Domains: %d\n", plan.Domains)
+	fmt.Fprintf(w, "API Limits: %d concurrent assessments (%d in use), %s cool-off between new assessments\n", info.MaxAssessments, info.CurrentAssessments, plan.CoolOff)
+	fmt.Fprintf(w, "Available Slots: %d\n", plan.Slots)
+	fmt.Fprintf(w, "Assumed Assessment Time: %s\n", plan.Assessment)
+	fmt.Fprintf(w, "Batches: %d\n", plan.Batches)
+	fmt.Fprintf(w, "Cool-off Waits: %s\n", plan.CoolOffs.Round(time.Second))
+	fmt.Fprintf(w, "Estimated Run Time: %s\n", plan.Duration.Round(time.Second))
+	fmt.Fprintf(w, "Estimated Completion: %s\n", formatTime(now.Add(plan.Duration)))
+}
+
+// runPlanCommand implements the plan subcommand: it reads the current API limits and
+// estimates how long assessing a domain list would take, without starting anything.
+// It returns the process exit code.
+func runPlanCommand(args []string) int {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	assessment := fs.Duration("assessment-time", 90*time.Second, "Assumed duration of a single assessment")
+	slots := fs.Int("slots", 0, "Plan for this many concurrent assessments instead of the slots currently free")
+	resultsFile := fs.String("results-file", "", "Write the results to this file instead of stdout")
+	logFile := fs.String("log-file", "", "Append progress and status messages to this file instead of stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ssl-checker plan [flags] domains.txt")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	// Redirect the logs if requested
+	closeLog, err := redirectLogs(*logFile)
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	defer closeLog()
+	domains, err := readDomainList(fs.Arg(0))
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	info, err := ssllabs.NewClient().CheckApiStatus(context.Background())
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	available := info.MaxAssessments - info.CurrentAssessments
+	if *slots > 0 {
+		available = *slots
+	}
+	if available < 1 {
+		logf("No assessment slot is free right now (%d of %d in use), planning with 1\n", info.CurrentAssessments, info.MaxAssessments)
+		available = 1
+	}
+	coolOff := time.Duration(info.NewAssessmentCoolOff) * time.Millisecond
+	err = writeResultsWith(*resultsFile, func(w io.Writer) error {
+		displayPlan(w, info, planRun(len(domains), available, coolOff, *assessment), time.Now())
+		return nil
+	})
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+// Exit code used when the assessment completed but violates the policy
+const exitPolicyViolation = 2
+
+// Ways of treating the +/- modifiers of a grade when comparing against a minimum grade
+const (
+	gradeModifiersStrict = "strict"
+	gradeModifiersIgnore = "ignore"
+)
+
+// gradeLetters lists the grade letters from best to worst
+var gradeLetters = "ABCDEF"
+
+// gradeRank returns a comparable rank for a grade, higher is better, or -1 if the grade is unknown.
+// With the ignore mode A+, A and A- share the same rank.
+func gradeRank(grade, modifiers string) int {
+	if grade == "" {
+		return -1
+	}
+	// Trust (T) and mismatch (M) grades rank with F
+	letter := strings.IndexByte(gradeLetters, grade[0])
+	if grade == "T" || grade == "M" {
+		letter = strings.IndexByte(gradeLetters, 'F')
+	}
+	if letter < 0 || (grade[1:] != "" && grade[1:] != "+" && grade[1:] != "-") {
+		return -1
+	}
+	rank := (len(gradeLetters) - letter) * 3
+	if modifiers == gradeModifiersStrict {
+		switch grade[1:] {
+		case "+":
+			rank++
+		case "-":
+			rank--
+		}
+	}
+	return rank
+}
+
+// Structs to describe the policy the assessment results are evaluated against
+type policy struct {
+	minGrade  string
+	modifiers string
+	// bestEffort endpoints are reported but ignored when evaluating the policy,
+	// e.g. anycast CDN nodes outside of our control
+	bestEffort []*net.IPNet
+}
+
+// newPolicy validates the policy flags and returns the resulting policy
+func newPolicy(minGrade, modifiers, bestEffort string) (*policy, error) {
+	if modifiers != gradeModifiersStrict && modifiers != gradeModifiersIgnore {
+		return nil, fmt.Errorf("invalid grade modifiers %q: expected %s or %s", modifiers, gradeModifiersStrict, gradeModifiersIgnore)
+	}
+	if minGrade != "" && gradeRank(minGrade, gradeModifiersStrict) < 0 {
+		return nil, fmt.Errorf("invalid minimum grade %q", minGrade)
+	}
+	p := &policy{minGrade: minGrade, modifiers: modifiers}
+	// Parse the best-effort endpoints as IP addresses or CIDR ranges
+	for _, entry := range strings.Split(bestEffort, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid best-effort endpoint %q", entry)
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			p.bestEffort = append(p.bestEffort, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid best-effort endpoint %q: %v", entry, err)
+		}
+		p.bestEffort = append(p.bestEffort, network)
+	}
+	return p, nil
+}
+
+// isBestEffort reports whether an endpoint is ignored when evaluating the policy
+func (p *policy) isBestEffort(endpoint ssllabs.Endpoint) bool {
+	ip := net.ParseIP(endpoint.IpAddress)
+	if ip == nil {
+		return false
+	}
+	for _, network := range p.bestEffort {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// assessmentStatus returns the status of an assessment followed by its status message
+func assessmentStatus(host *ssllabs.Host) string {
+	if host.StatusMessage != "" {
+		return host.Status + ": " + host.StatusMessage
+	}
+	return host.Status
+}
+
+// violations returns a description of every policy violation of a result: endpoints graded
+// below the minimum grade, expired certificates and insecure subresources found by
+// -mixed-content. Best-effort endpoints are skipped. With a minimum grade, an assessment
+// that did not complete is a violation too, since none of its endpoints could be graded.
+func (p *policy) violations(result *ResultDocument, now time.Time) []string {
+	host := result.Host
+	var violations []string
+	if p.minGrade != "" && host.Status != "READY" {
+		violations = append(violations, fmt.Sprintf("assessment of %s did not complete (%s), below minimum %s", host.Host, assessmentStatus(host), p.minGrade))
+	}
+	for _, endpoint := range host.Endpoints {
+		if p.isBestEffort(endpoint) {
+			continue
+		}
+		if p.minGrade != "" && gradeRank(endpoint.Grade, p.modifiers) < gradeRank(p.minGrade, p.modifiers) {
+			grade := endpoint.Grade
+			if grade == "" {
+				grade = "no grade"
+			}
+			violations = append(violations, fmt.Sprintf("%s graded %s, below minimum %s", endpoint.IpAddress, grade, p.minGrade))
+		}
+		// Expired certificates always violate the policy
+		if finding := expiredCertFinding(host, endpoint, now); finding != "" {
+			violations = append(violations, fmt.Sprintf("%s %s", endpoint.IpAddress, finding))
+		}
+	}
+	for _, url := range result.MixedContent {
+		violations = append(violations, fmt.Sprintf("homepage loads insecure subresource %s", url))
+	}
+	return violations
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+// runPrefetch implements the prefetch subcommand: it starts an assessment for every domain
+// of a list without waiting for the results, so that later runs can reuse them with
+// -max-result-age. With -max-result-age, domains that already have a fresh result are
+// reported as cached and not assessed again. It returns the process exit code.
+func runPrefetch(args []string) int {
+	fs := flag.NewFlagSet("prefetch", flag.ExitOnError)
+	publish := fs.Bool("publish", false, "Publish results on SSL Labs board")
+	assumeYes := fs.Bool("yes", false, "Publish without asking for confirmation")
+	verifyToken := fs.String("verify-ownership", "", "With -publish, skip domains that do not publish this token")
+	maxResultAge := fs.Duration("max-result-age", 0, "Skip domains with a cached SSL Labs result newer than this age (e.g., 24h)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ssl-checker prefetch [-max-result-age age] [-publish [-yes] [-verify-ownership token]] domains.txt")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	domains, err := readDomainList(fs.Arg(0))
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	// Make sure publishing is intended before anything is submitted
+	if *publish {
+		if err := confirmPublish(domains, *assumeYes); err != nil {
+			logf("Error: %v\n", err)
+			return 1
+		}
+	}
+	// Ctrl-C interrupts the wait for capacity instead of leaving it to finish its sleep
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	sslClient := ssllabs.NewClient()
+	failed, cached := 0, 0
+	for i, domain := range domains {
+		// Skip domains whose ownership is not verified rather than publishing their results
+		if *publish && *verifyToken != "" {
+			if err := verifyOwnership(ctx, domain, *verifyToken, 10*time.Second); err != nil {
+				logf("[%d/%d] %s: %v\n", i+1, len(domains), domain, err)
+				failed++
+				continue
+			}
+		}
+		// Wait for a free assessment slot and the cool-off period
+		if err := sslClient.WaitForCapacity(ctx); err != nil {
+			logf("Error: %v\n", err)
+			return 1
+		}
+		var host *ssllabs.Host
+		if *maxResultAge > 0 {
+			// Reuse a fresh cached result, a new assessment is started otherwise
+			host, err = sslClient.CachedAssessment(ctx, domain, *maxResultAge, *publish)
+		} else {
+			host, err = sslClient.StartAssessment(ctx, domain, *publish)
+		}
+		if err != nil {
+			logf("[%d/%d] %s: %v\n", i+1, len(domains), domain, err)
+			failed++
+			continue
+		}
+		// CachedAssessment only returns a completed result when it is fresh
+		if *maxResultAge > 0 && host.Status == "READY" {
+			logf("[%d/%d] %s: cached, tested %s\n", i+1, len(domains), domain, formatTime(time.UnixMilli(host.TestTime)))
+			cached++
+			continue
+		}
+		logf("[%d/%d] %s: %s\n", i+1, len(domains), domain, host.Status)
+	}
+	logf("Prefetch submitted %d of %d domains, %d already cached\n", len(domains)-failed-cached, len(domains), cached)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Structs to describe the outcome of a direct TLS probe of a service
+type probeResult struct {
+	Target              string
+	Role                string
+	Version             uint16
+	CipherSuite         uint16
+	Certificates        []*x509.Certificate
+	ClientCertRequested bool
+	// LegacyVersions lists the protocols older than TLS 1.2 the service also accepts
+	LegacyVersions []uint16
+	// Upgraded is set when the application protocol check over TLS succeeded, UpgradeError
+	// when it failed
+	Upgraded     bool
+	UpgradeError error
+	// Protocol is the application protocol negotiated with ALPN, ExpectedProtocol the one
+	// the service is required to negotiate
+	Protocol         string
+	ExpectedProtocol string
+	// ClientCertRequired is set when the service rejected the handshake without a client certificate
+	ClientCertRequired bool
+	// VerifyError is set when the certificate chain or host name does not verify
+	VerifyError error
+	// Err is set when no certificate could be read from the service
+	Err error
+}
+
+// Structs to describe a service to probe
+type probeTarget struct {
+	Host string
+	Port int
+	// Role describes what the service is, e.g. "kube-apiserver"
+	Role string
+	// Negotiate upgrades a plaintext connection to TLS for protocols that do not start
+	// with a TLS handshake, it is nil for services speaking TLS from the first byte
+	Negotiate func(conn net.Conn) error
+	// ALPN is the application protocol offered during the handshake, which the service
+	// must then negotiate, e.g. "h2" for gRPC
+	ALPN string
+	// Upgrade checks the application protocol once the TLS handshake succeeded, e.g. the
+	// WebSocket opening handshake, and Path is the resource it requests
+	Upgrade func(conn net.Conn, target probeTarget) error
+	Path    string
+	// ServerName is sent in SNI and the certificate is verified for it, the host is used
+	// if empty. It is set to reach a server by address under the name of the site it
+	// serves, e.g. the origin behind a CDN.
+	ServerName string
+}
+
+// address returns the host:port address of the target
+func (t probeTarget) address() string {
+	return net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+}
+
+// serverName returns the name sent in SNI and verified in the certificate of the target
+func (t probeTarget) serverName() string {
+	if t.ServerName != "" {
+		return t.ServerName
+	}
+	return t.Host
+}
+
+// Structs to describe how services are probed
+type prober struct {
+	timeout time.Duration
+	// roots verifies certificates, the system pool is used if nil
+	roots *x509.CertPool
+	// expiryWarning is how long before expiry a certificate is flagged
+	expiryWarning time.Duration
+}
+
+// Default delay before certificate expiry from which a probed certificate is flagged
+const defaultProbeExpiryWarning = 30 * expiryDay
+
+// loadCAFile reads a PEM bundle of CA certificates to verify probed services against
+func loadCAFile(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in CA file %s", path)
+	}
+	return pool, nil
+}
+
+// probe connects to a target and performs a TLS handshake, recording the negotiated
+// parameters and certificates even when the handshake fails later on, e.g. because the
+// service requires a client certificate
+func (p *prober) probe(target probeTarget) probeResult {
+	result := probeResult{Target: target.address(), Role: target.Role, ExpectedProtocol: target.ALPN}
+	conn, err := p.connect(target)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer conn.Close()
+	// Verification is done separately so certificates are recorded even if they are invalid.
+	// Legacy protocols are allowed so services only speaking them are reported as such.
+	config := &tls.Config{
+		ServerName:         target.serverName(),
+		MinVersion:         tls.VersionTLS10,
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			result.Version = state.Version
+			result.CipherSuite = state.CipherSuite
+			result.Certificates = state.PeerCertificates
+			result.Protocol = state.NegotiatedProtocol
+			return nil
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			result.ClientCertRequested = true
+			return &tls.Certificate{}, nil
+		},
+	}
+	if target.ALPN != "" {
+		config.NextProtos = []string{target.ALPN}
+	}
+	tlsConn := tls.Client(conn, config)
+	handshakeErr := tlsConn.Handshake()
+	if result.ClientCertRequested {
+		result.ClientCertRequired = clientCertRejected(tlsConn, handshakeErr)
+	}
+	if len(result.Certificates) == 0 {
+		if handshakeErr == nil {
+			handshakeErr = fmt.Errorf("no certificate presented")
+		}
+		result.Err = fmt.Errorf("TLS handshake failed: %v", handshakeErr)
+		// Services that do not speak the offered protocol may abort the handshake instead
+		// of ignoring ALPN
+		if target.ALPN != "" && strings.Contains(handshakeErr.Error(), "no application protocol") {
+			result.Err = fmt.Errorf("ALPN %s not negotiated, %s requires it: %v", target.ALPN, target.Role, handshakeErr)
+		}
+		return result
+	}
+	result.VerifyError = p.verify(target.serverName(), result.Certificates)
+	// Only the highest common version is negotiated, so older ones are offered one by one
+	for _, version := range []uint16{tls.VersionTLS10, tls.VersionTLS11} {
+		if version < result.Version && p.acceptsVersion(target, version) {
+			result.LegacyVersions = append(result.LegacyVersions, version)
+		}
+	}
+	if target.Upgrade != nil {
+		if result.ClientCertRequired {
+			result.UpgradeError = fmt.Errorf("%s upgrade not attempted, a client certificate is required", target.Role)
+			return result
+		}
+		conn.SetDeadline(time.Now().Add(p.timeout))
+		if err := target.Upgrade(tlsConn, target); err != nil {
+			result.UpgradeError = fmt.Errorf("%s upgrade failed: %v", target.Role, err)
+		} else {
+			result.Upgraded = true
+		}
+	}
+	return result
+}
+
+// connect opens a connection to a target, negotiating TLS first for protocols that require it
+func (p *prober) connect(target probeTarget) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", target.address(), p.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(p.timeout))
+	// Ask the service to switch to TLS first if its protocol requires it
+	if target.Negotiate != nil {
+		if err := target.Negotiate(conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to negotiate TLS: %v", err)
+		}
+	}
+	return conn, nil
+}
+
+// acceptsVersion reports whether a target accepts a handshake limited to the given protocol
+// version. The server certificate is enough to tell, so services requiring a client
+// certificate are detected too.
+func (p *prober) acceptsVersion(target probeTarget, version uint16) bool {
+	conn, err := p.connect(target)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	accepted := false
+	config := &tls.Config{
+		ServerName:         target.serverName(),
+		MinVersion:         version,
+		MaxVersion:         version,
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			accepted = state.Version == version
+			return nil
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &tls.Certificate{}, nil
+		},
+	}
+	if target.ALPN != "" {
+		config.NextProtos = []string{target.ALPN}
+	}
+	tls.Client(conn, config).Handshake()
+	return accepted
+}
+
+// Time to wait for a service to reject a handshake made without a client certificate
+const clientCertRejectionWait = time.Second
+
+// clientCertRejected reports whether a service rejected a handshake made without a client
+// certificate. With TLS 1.3 the client finishes its handshake before the server checks the
+// certificate, so the rejection only shows up as an alert on the first read.
+func clientCertRejected(conn *tls.Conn, handshakeErr error) bool {
+	if handshakeErr != nil {
+		return true
+	}
+	conn.SetReadDeadline(time.Now().Add(clientCertRejectionWait))
+	_, err := conn.Read(make([]byte, 1))
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return false
+	}
+	return err != nil
+}
+
+// verify checks the certificate chain against the trusted roots and the host name
+func (p *prober) verify(host string, certs []*x509.Certificate) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         p.roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+// findings returns the problems found on a probed service. Critical findings are
+// unreachable services, expired certificates, protocols older than TLS 1.2 whether
+// negotiated or merely accepted, and services not negotiating or not upgrading to their
+// required application protocol.
+func (p *prober) findings(result probeResult, now time.Time) (critical, warnings []string) {
+	if result.Err != nil {
+		return []string{result.Err.Error()}, nil
+	}
+	if result.Version < tls.VersionTLS12 {
+		critical = append(critical, fmt.Sprintf("negotiated %s, TLS 1.2 or later is expected", tls.VersionName(result.Version)))
+	}
+	for _, version := range result.LegacyVersions {
+		critical = append(critical, fmt.Sprintf("accepts %s, TLS 1.2 or later is expected", tls.VersionName(version)))
+	}
+	if result.ExpectedProtocol != "" && result.Protocol != result.ExpectedProtocol {
+		critical = append(critical, fmt.Sprintf("ALPN %s not negotiated, %s requires it", result.ExpectedProtocol, result.Role))
+	}
+	if result.UpgradeError != nil {
+		critical = append(critical, result.UpgradeError.Error())
+	}
+	leaf := result.Certificates[0]
+	switch {
+	case expired(leaf.NotAfter, now):
+		critical = append(critical, fmt.Sprintf("certificate expired %s", relativeTime(leaf.NotAfter, now)))
+	case expiresWithin(leaf.NotAfter, now, p.expiryWarning):
+		warnings = append(warnings, fmt.Sprintf("certificate expires %s", relativeTime(leaf.NotAfter, now)))
+	}
+	if result.VerifyError != nil {
+		warnings = append(warnings, fmt.Sprintf("certificate does not verify: %v", result.VerifyError))
+	}
+	return critical, warnings
+}
+
+// displayProbeResult prints the outcome of a probe and its findings to the given writer
+func displayProbeResult(w io.Writer, result probeResult, critical, warnings []string) {
+	if result.Role != "" {
+		fmt.Fprintf(w, "Service: %s (%s)\n", result.Target, result.Role)
+	} else {
+		fmt.Fprintf(w, "Service: %s\n", result.Target)
+	}
+	if result.Err == nil {
+		displayProbeDetails(w, result)
+	}
+	displayFindings(w, critical, warnings)
+	fmt.Fprintln(w)
+}
+
+// displayProbeDetails prints the negotiated protocol and certificate of a successful probe
+func displayProbeDetails(w io.Writer, result probeResult) {
+	leaf := result.Certificates[0]
+	fmt.Fprintf(w, "  Protocol: %s\n", tls.VersionName(result.Version))
+	fmt.Fprintf(w, "  Cipher Suite: %s\n", tls.CipherSuiteName(result.CipherSuite))
+	if len(result.LegacyVersions) > 0 {
+		var names []string
+		for _, version := range result.LegacyVersions {
+			names = append(names, tls.VersionName(version))
+		}
+		fmt.Fprintf(w, "  Legacy Protocols: %s\n", strings.Join(names, ", "))
+	}
+	if result.ExpectedProtocol != "" {
+		protocol := result.Protocol
+		if protocol == "" {
+			protocol = "none"
+		}
+		fmt.Fprintf(w, "  ALPN Protocol: %s\n", protocol)
+	}
+	if result.Upgraded {
+		fmt.Fprintln(w, "  Upgrade: 101 Switching Protocols")
+	}
+	fmt.Fprintf(w, "  Certificate Subject: %s\n", leaf.Subject.CommonName)
+	if names := append(append([]string{}, leaf.DNSNames...), ipStrings(leaf.IPAddresses)...); len(names) > 0 {
+		fmt.Fprintf(w, "  Certificate Names: %s\n", strings.Join(names, ", "))
+	}
+	fmt.Fprintf(w, "  Certificate Issuer: %s\n", leaf.Issuer.CommonName)
+	fmt.Fprintf(w, "  Certificate Valid: %s to %s\n", formatTime(leaf.NotBefore), formatTime(leaf.NotAfter))
+	switch {
+	case result.ClientCertRequired:
+		fmt.Fprintln(w, "  Client Certificate: required")
+	case result.ClientCertRequested:
+		fmt.Fprintln(w, "  Client Certificate: requested")
+	default:
+		fmt.Fprintln(w, "  Client Certificate: not requested")
+	}
+}
+
+// displayFindings prints critical findings followed by warnings
+func displayFindings(w io.Writer, critical, warnings []string) {
+	for _, finding := range critical {
+		fmt.Fprintf(w, "  CRITICAL: %s\n", finding)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "  Warning: %s\n", warning)
+	}
+}
+
+// ipStrings formats a list of IP addresses
+func ipStrings(ips []net.IP) []string {
+	var result []string
+	for _, ip := range ips {
+		result = append(result, ip.String())
+	}
+	return result
+}
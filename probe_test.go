@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// startTLSServer listens on a local port with the given protocol range and completes the
+// handshake of every connection
+func startTLSServer(t *testing.T, minVersion, maxVersion uint16) probeTarget {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.StartTLS()
+	certificates := server.TLS.Certificates
+	server.Close()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: certificates, MinVersion: minVersion, MaxVersion: maxVersion})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	n, _ := strconv.Atoi(port)
+	return probeTarget{Host: host, Port: n}
+}
+
+func TestProbeLegacyVersions(t *testing.T) {
+	tests := []struct {
+		name                   string
+		minVersion, maxVersion uint16
+		wantVersion            uint16
+		wantLegacy             int
+	}{
+		{"modern only", tls.VersionTLS12, tls.VersionTLS13, tls.VersionTLS13, 0},
+		{"modern and legacy", tls.VersionTLS10, tls.VersionTLS13, tls.VersionTLS13, 2},
+		{"legacy only", tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS11, 1},
+	}
+	p := &prober{timeout: 2 * time.Second}
+	for _, tt := range tests {
+		result := p.probe(startTLSServer(t, tt.minVersion, tt.maxVersion))
+		if result.Err != nil {
+			t.Fatalf("%s: probe failed: %v", tt.name, result.Err)
+		}
+		if result.Version != tt.wantVersion {
+			t.Errorf("%s: negotiated %s, want %s", tt.name, tls.VersionName(result.Version), tls.VersionName(tt.wantVersion))
+		}
+		if len(result.LegacyVersions) != tt.wantLegacy {
+			t.Errorf("%s: legacy versions = %v, want %d", tt.name, result.LegacyVersions, tt.wantLegacy)
+		}
+		critical, _ := p.findings(result, time.Now())
+		if wantCritical := tt.wantVersion < tls.VersionTLS12 || tt.wantLegacy > 0; (len(critical) > 0) != wantCritical {
+			t.Errorf("%s: critical findings = %q", tt.name, critical)
+		}
+	}
+}
+
+func TestCompareOrigin(t *testing.T) {
+	p := &prober{timeout: 2 * time.Second}
+	edge := p.probe(startTLSServer(t, tls.VersionTLS12, tls.VersionTLS13))
+	tests := []struct {
+		name                   string
+		minVersion, maxVersion uint16
+		wantCritical           int
+		wantWarnings           int
+	}{
+		{"same settings", tls.VersionTLS12, tls.VersionTLS13, 0, 0},
+		{"legacy protocols", tls.VersionTLS10, tls.VersionTLS13, 2, 0},
+		{"older maximum", tls.VersionTLS12, tls.VersionTLS12, 0, 1},
+	}
+	for _, tt := range tests {
+		target := startTLSServer(t, tt.minVersion, tt.maxVersion)
+		// The test certificate is valid for example.com, which the origin is probed as
+		target.ServerName = "example.com"
+		origin := p.probe(target)
+		if origin.Err != nil {
+			t.Fatalf("%s: probe failed: %v", tt.name, origin.Err)
+		}
+		critical, warnings := originFindings(edge, origin)
+		if len(critical) != tt.wantCritical || len(warnings) != tt.wantWarnings {
+			t.Errorf("%s: originFindings = %q, %q, want %d critical and %d warnings", tt.name, critical, warnings, tt.wantCritical, tt.wantWarnings)
+		}
+	}
+	// An origin that cannot be reached directly is not a failure
+	critical, warnings := originFindings(edge, probeResult{Err: errors.New("connection refused")})
+	if len(critical) != 0 || len(warnings) != 1 {
+		t.Errorf("unreachable origin: originFindings = %q, %q, want one warning", critical, warnings)
+	}
+}
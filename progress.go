@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+// Structs to describe a structured progress event emitted while polling
+type ProgressEvent struct {
+	Time          string             `json:"time"`
+	Domain        string             `json:"domain"`
+	Port          int                `json:"port,omitempty"`
+	Protocol      string             `json:"protocol,omitempty"`
+	Status        string             `json:"status"`
+	StatusMessage string             `json:"statusMessage,omitempty"`
+	Endpoints     []EndpointProgress `json:"endpoints"`
+}
+
+// Structs to describe the progress of a single endpoint in a progress event
+type EndpointProgress struct {
+	IpAddress     string `json:"ipAddress"`
+	StatusDetails string `json:"statusDetails,omitempty"`
+	Progress      int    `json:"progress"`
+	Eta           int    `json:"eta"`
+}
+
+// newProgressEvent builds a progress event from the current assessment status
+func newProgressEvent(host *ssllabs.Host, now time.Time) ProgressEvent {
+	event := ProgressEvent{
+		Time:          now.UTC().Format(time.RFC3339),
+		Domain:        host.Host,
+		Port:          host.Port,
+		Protocol:      host.Protocol,
+		Status:        host.Status,
+		StatusMessage: host.StatusMessage,
+		Endpoints:     []EndpointProgress{},
+	}
+	for _, endpoint := range host.Endpoints {
+		event.Endpoints = append(event.Endpoints, EndpointProgress{
+			IpAddress:     endpoint.IpAddress,
+			StatusDetails: endpoint.StatusDetails,
+			Progress:      endpoint.Progress,
+			Eta:           endpoint.Eta,
+		})
+	}
+	return event
+}
+
+// writeProgressEvent writes a progress event as a single JSON line
+func writeProgressEvent(w io.Writer, event ProgressEvent) error {
+	return json.NewEncoder(w).Encode(event)
+}
+
+// writeProgressFile replaces the progress file with the latest progress event. The event is
+// written to a temporary file first so readers never see a partially written file.
+func writeProgressFile(path string, event ProgressEvent) error {
+	data, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode progress: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".progress-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create progress file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write progress file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write progress file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace progress file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// publishWarning explains what publishing an assessment means
+const publishWarning = `WARNING: -publish posts the results for %s on the public SSL Labs board.
+Anyone can see the grade and the weaknesses found, and published results are
+listed among recent assessments. Only publish results for hosts you own.
+`
+
+// confirmPublish warns about publishing results and asks for confirmation on the terminal.
+// Without a terminal, publishing requires -yes so scripts cannot publish by accident.
+func confirmPublish(domains []string, assumeYes bool) error {
+	logf(publishWarning, strings.Join(domains, ", "))
+	if assumeYes {
+		return nil
+	}
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return fmt.Errorf("refusing to publish without confirmation: pass -yes to publish non-interactively")
+	}
+	return readConfirmation(os.Stdin)
+}
+
+// readConfirmation prompts for "yes" and fails on any other answer
+func readConfirmation(r io.Reader) error {
+	logf("Type yes to publish: ")
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && answer == "" {
+		logln()
+		return fmt.Errorf("no confirmation read: pass -yes to publish non-interactively")
+	}
+	if strings.TrimSpace(strings.ToLower(answer)) != "yes" {
+		return fmt.Errorf("publishing not confirmed")
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// RDP security protocols requested in the negotiation request: TLS and CredSSP
+const (
+	rdpProtocolSSL    = 0x00000001
+	rdpProtocolHybrid = 0x00000002
+)
+
+// RDP negotiation message types
+const (
+	rdpNegResponse = 0x02
+	rdpNegFailure  = 0x03
+)
+
+// negotiateRDP sends an X.224 Connection Request asking for the TLS security layer and
+// checks the server selected it in its Connection Confirm
+func negotiateRDP(conn net.Conn) error {
+	// TPKT header, X.224 Connection Request and RDP_NEG_REQ
+	request := []byte{
+		0x03, 0x00, 0x00, 0x13,
+		0x0e, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	binary.LittleEndian.PutUint32(request[15:], rdpProtocolSSL|rdpProtocolHybrid)
+	if _, err := conn.Write(request); err != nil {
+		return fmt.Errorf("failed to send connection request: %v", err)
+	}
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("failed to read connection confirm: %v", err)
+	}
+	length := int(binary.BigEndian.Uint16(header[2:]))
+	if header[0] != 0x03 || length < 4 {
+		return fmt.Errorf("invalid connection confirm")
+	}
+	confirm := make([]byte, length-4)
+	if _, err := io.ReadFull(conn, confirm); err != nil {
+		return fmt.Errorf("failed to read connection confirm: %v", err)
+	}
+	// The negotiation response follows the 7 byte X.224 Connection Confirm
+	if len(confirm) < 15 {
+		return fmt.Errorf("server only supports standard RDP security")
+	}
+	selected := binary.LittleEndian.Uint32(confirm[11:])
+	switch confirm[7] {
+	case rdpNegResponse:
+		if selected&(rdpProtocolSSL|rdpProtocolHybrid) == 0 {
+			return fmt.Errorf("server selected standard RDP security")
+		}
+		return nil
+	case rdpNegFailure:
+		return fmt.Errorf("server refused TLS security (failure code %d)", selected)
+	default:
+		return fmt.Errorf("unexpected negotiation message type %d", confirm[7])
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// readBaseline reads the result of the given domain from a JSON result file written by
+// -output json or report merge
+func readBaseline(path, domain string) (*ResultDocument, error) {
+	results, err := readResultsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if result.Host.Host == domain {
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("baseline %s holds no result for %s", path, domain)
+}
+
+// regressions returns the findings of a result that are new or worse than in the baseline:
+// an assessment that did not complete, lower grades, new warnings, certificates that
+// expired since the baseline and new insecure subresources. Endpoints are matched by IP
+// address, and endpoints missing from the baseline are compared to the worst graded
+// baseline endpoint and to every baseline warning. Best-effort endpoints are skipped.
+func (p *policy) regressions(baselineResult, result *ResultDocument, now time.Time) []string {
+	baseline, host := baselineResult.Host, result.Host
+	var regressions []string
+	// Nothing can be compared without results, which never counts as unchanged
+	if host.Status != "READY" {
+		regressions = append(regressions, fmt.Sprintf("assessment of %s did not complete (%s)", host.Host, assessmentStatus(host)))
+	}
+	// Collect what the baseline already accepted for endpoints it did not see
+	worstGrade := ""
+	allWarnings := make(map[string]bool)
+	for _, endpoint := range baseline.Endpoints {
+		rank := gradeRank(endpoint.Grade, p.modifiers)
+		if rank >= 0 && (worstGrade == "" || rank < gradeRank(worstGrade, p.modifiers)) {
+			worstGrade = endpoint.Grade
+		}
+		for _, warning := range endpointWarnings(endpoint) {
+			allWarnings[warning] = true
+		}
+	}
+	baselineTime := time.UnixMilli(baseline.TestTime)
+	for _, endpoint := range host.Endpoints {
+		if p.isBestEffort(endpoint) {
+			continue
+		}
+		previousGrade, previousWarnings, previousExpired := worstGrade, allWarnings, false
+		for _, previous := range baseline.Endpoints {
+			if previous.IpAddress != endpoint.IpAddress {
+				continue
+			}
+			previousGrade = previous.Grade
+			previousWarnings = make(map[string]bool)
+			for _, warning := range endpointWarnings(previous) {
+				previousWarnings[warning] = true
+			}
+			previousExpired = certExpired(previous.Details.Cert, baselineTime)
+		}
+		if gradeRank(endpoint.Grade, p.modifiers) < gradeRank(previousGrade, p.modifiers) {
+			grade := endpoint.Grade
+			if grade == "" {
+				grade = "no grade"
+			}
+			regressions = append(regressions, fmt.Sprintf("%s graded %s, was %s in the baseline", endpoint.IpAddress, grade, previousGrade))
+		}
+		for _, warning := range endpointWarnings(endpoint) {
+			if !previousWarnings[warning] {
+				regressions = append(regressions, fmt.Sprintf("%s new warning: %s", endpoint.IpAddress, warning))
+			}
+		}
+		if finding := expiredCertFinding(host, endpoint, now); finding != "" && !previousExpired {
+			regressions = append(regressions, fmt.Sprintf("%s %s", endpoint.IpAddress, finding))
+		}
+	}
+	known := make(map[string]bool)
+	for _, url := range baselineResult.MixedContent {
+		known[url] = true
+	}
+	for _, url := range result.MixedContent {
+		if !known[url] {
+			regressions = append(regressions, fmt.Sprintf("homepage loads new insecure subresource %s", url))
+		}
+	}
+	return regressions
+}
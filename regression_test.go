@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+func TestRegressionsIncompleteAssessment(t *testing.T) {
+	p, err := newPolicy("A", gradeModifiersIgnore, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline := &ssllabs.Host{Host: "example.com", Status: "READY", Endpoints: []ssllabs.Endpoint{{IpAddress: "192.0.2.1", Grade: "A"}}}
+	host := &ssllabs.Host{Host: "example.com", Status: "ERROR", StatusMessage: "Unable to resolve domain name"}
+	regressions := p.regressions(newResultDocument(baseline, nil, nil), newResultDocument(host, nil, nil), time.Now())
+	if len(regressions) != 1 || !strings.Contains(regressions[0], "did not complete") {
+		t.Errorf("regressions = %q, want the incomplete assessment", regressions)
+	}
+}
+
+func TestRegressionsNewEndpointSkipsUngradedBaseline(t *testing.T) {
+	p, err := newPolicy("", gradeModifiersIgnore, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline := &ssllabs.Host{Host: "example.com", Status: "READY", Endpoints: []ssllabs.Endpoint{
+		{IpAddress: "192.0.2.1", Grade: "B"},
+		{IpAddress: "192.0.2.2", Grade: ""},
+		{IpAddress: "192.0.2.3", Grade: "A"},
+	}}
+	tests := []struct {
+		grade string
+		want  int
+	}{
+		{"B", 0},
+		{"A", 0},
+		{"C", 1},
+	}
+	for _, tt := range tests {
+		host := &ssllabs.Host{Host: "example.com", Status: "READY", Endpoints: []ssllabs.Endpoint{{IpAddress: "192.0.2.9", Grade: tt.grade}}}
+		if regressions := p.regressions(newResultDocument(baseline, nil, nil), newResultDocument(host, nil, nil), time.Now()); len(regressions) != tt.want {
+			t.Errorf("new endpoint graded %s: regressions = %q, want %d", tt.grade, regressions, tt.want)
+		}
+	}
+}
+
+func TestRegressionsUnchanged(t *testing.T) {
+	p, err := newPolicy("A", gradeModifiersIgnore, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := &ssllabs.Host{Host: "example.com", Status: "READY", Endpoints: []ssllabs.Endpoint{{IpAddress: "192.0.2.1", Grade: "B"}}}
+	if regressions := p.regressions(newResultDocument(host, nil, nil), newResultDocument(host, nil, nil), time.Now()); len(regressions) != 0 {
+		t.Errorf("regressions = %q, want none", regressions)
+	}
+}
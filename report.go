@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+// runReport implements the report subcommand and its actions. It returns the process exit code.
+func runReport(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: ssl-checker report merge [-o merged.json] a.json b.json ...")
+		fmt.Fprintln(os.Stderr, "       ssl-checker report risk [-previous old.json] a.json b.json ...")
+		return 1
+	}
+	switch args[0] {
+	case "merge":
+		return runReportMerge(args[1:])
+	case "risk":
+		return runReportRisk(args[1:])
+	default:
+		logf("Error: unknown report action %q\n", args[0])
+		return 1
+	}
+}
+
+// runReportMerge combines JSON result files into a single dataset holding the most recent
+// result of each host. It returns the process exit code.
+func runReportMerge(args []string) int {
+	fs := flag.NewFlagSet("report merge", flag.ExitOnError)
+	out := fs.String("o", "", "Write the merged results to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ssl-checker report merge [-o merged.json] a.json b.json ...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 1
+	}
+	var results []*ResultDocument
+	for _, path := range fs.Args() {
+		read, err := readResultsFile(path)
+		if err != nil {
+			logf("Error: %v\n", err)
+			return 1
+		}
+		results = append(results, read...)
+	}
+	merged := mergeResults(results)
+	logf("Merged %d results into %d hosts\n", len(results), len(merged))
+	err := writeResultsWith(*out, func(w io.Writer) error {
+		return writeJSON(w, merged)
+	})
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// readResultsFile reads a JSON result file, holding either a single result as written by
+// -output json or a list of results as written by report merge
+func readResultsFile(path string) ([]*ResultDocument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results file: %v", err)
+	}
+	var results []*ResultDocument
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &results)
+	} else {
+		var result ResultDocument
+		err = json.Unmarshal(data, &result)
+		results = append(results, &result)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse results file %s: %v", path, err)
+	}
+	// Every result must name its host, otherwise it cannot be matched or merged
+	for i, result := range results {
+		if result == nil || result.Host == nil || result.Host.Host == "" {
+			return nil, fmt.Errorf("invalid results file %s: result %d has no host", path, i+1)
+		}
+	}
+	return results, nil
+}
+
+// mergeResults keeps the most recent result of each host and port with its notes, sorted
+// by host and in canonical form
+func mergeResults(results []*ResultDocument) []*ResultDocument {
+	latest := make(map[string]*ResultDocument)
+	for _, result := range results {
+		key := fmt.Sprintf("%s:%d", result.Host.Host, result.Port)
+		// On conflicts the result tested last wins
+		if current, ok := latest[key]; !ok || result.TestTime > current.TestTime {
+			latest[key] = result
+		}
+	}
+	merged := make([]*ResultDocument, 0, len(latest))
+	for _, result := range latest {
+		merged = append(merged, newResultDocument(result.Host, result.Notes, result.MixedContent).withDirect(result.Direct))
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].Host.Host != merged[j].Host.Host {
+			return merged[i].Host.Host < merged[j].Host.Host
+		}
+		return merged[i].Port < merged[j].Port
+	})
+	return merged
+}
+
+// readMergedResults reads and merges the hosts of several JSON result files
+func readMergedResults(paths []string) ([]*ssllabs.Host, error) {
+	var results []*ResultDocument
+	for _, path := range paths {
+		read, err := readResultsFile(path)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, read...)
+	}
+	var hosts []*ssllabs.Host
+	for _, result := range mergeResults(results) {
+		hosts = append(hosts, result.Host)
+	}
+	return hosts, nil
+}
+
+// runReportRisk prints the risk score of every domain and the organization-level rollup,
+// with the change since an earlier dataset when one is given. It returns the process exit code.
+func runReportRisk(args []string) int {
+	fs := flag.NewFlagSet("report risk", flag.ExitOnError)
+	previous := fs.String("previous", "", "Earlier results (e.g., last quarter's merged JSON) to report the trend against")
+	out := fs.String("o", "", "Write the risk report to this file instead of stdout")
+	logFile := fs.String("log-file", "", "Append progress and status messages to this file instead of stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ssl-checker report risk [-previous old.json] [-o risk.txt] a.json b.json ...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 1
+	}
+	// Redirect the logs if requested
+	closeLog, err := redirectLogs(*logFile)
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	defer closeLog()
+	hosts, err := readMergedResults(fs.Args())
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	var earlier []*ssllabs.Host
+	if *previous != "" {
+		if earlier, err = readMergedResults([]string{*previous}); err != nil {
+			logf("Error: %v\n", err)
+			return 1
+		}
+	}
+	err = writeResultsWith(*out, func(w io.Writer) error {
+		displayRiskReport(w, hosts, earlier, time.Now())
+		return nil
+	})
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// displayRiskReport writes the risk score of every domain and the organization-level
+// rollup, followed by the earlier rollup and the change when earlier results are given
+func displayRiskReport(w io.Writer, hosts, earlier []*ssllabs.Host, now time.Time) {
+	for _, host := range hosts {
+		fmt.Fprintf(w, "%s: %d/100\n", host.Host, domainRiskScore(host, now))
+	}
+	score := orgRiskScore(hosts, now)
+	if earlier == nil {
+		fmt.Fprintf(w, "Org Risk Score: %d/100 across %d domains\n", score, len(hosts))
+		return
+	}
+	// Expiry risk of the earlier dataset is evaluated when it was last tested
+	var earlierTime time.Time
+	for _, host := range earlier {
+		if t := time.UnixMilli(host.TestTime); t.After(earlierTime) {
+			earlierTime = t
+		}
+	}
+	before := orgRiskScore(earlier, earlierTime)
+	fmt.Fprintf(w, "Org Risk Score: %d/100 across %d domains (was %d/100 across %d domains, %+d)\n", score, len(hosts), before, len(earlier), score-before)
+}
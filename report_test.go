@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestFile writes data to a file of a temporary directory and returns its path
+func writeTestFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadResultsFileRejectsResultsWithoutHost(t *testing.T) {
+	for _, data := range []string{"null", "{}", "[null]", `[{"host":"example.com"},{}]`} {
+		if _, err := readResultsFile(writeTestFile(t, "results.json", data)); err == nil {
+			t.Errorf("readResultsFile(%s) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestMergeResultsKeepsLatest(t *testing.T) {
+	path := writeTestFile(t, "results.json", `[
+		{"host":"b.example","port":443,"testTime":1},
+		{"host":"a.example","port":443,"testTime":2,"notes":["old"]},
+		{"host":"a.example","port":443,"testTime":3,"notes":["new"]}
+	]`)
+	results, err := readResultsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := mergeResults(results)
+	if len(merged) != 2 {
+		t.Fatalf("merged %d results, want 2", len(merged))
+	}
+	if merged[0].Host.Host != "a.example" || merged[0].TestTime != 3 || len(merged[0].Notes) != 1 || merged[0].Notes[0] != "new" {
+		t.Errorf("merged[0] = %s at %d with notes %q, want a.example at 3 with notes [new]", merged[0].Host.Host, merged[0].TestTime, merged[0].Notes)
+	}
+	if merged[1].Host.Host != "b.example" {
+		t.Errorf("merged[1] = %s, want b.example", merged[1].Host.Host)
+	}
+}
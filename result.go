@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+// Structs to describe a result written by -output json and report merge: the host in
+// canonical form followed by the notes attached to the scan, the insecure subresources of
+// the homepage when -mixed-content checked them, the direct probe of -hybrid and the
+// content hash of all of them, so downstream storage can deduplicate results without
+// recomputing it
+type ResultDocument struct {
+	*ssllabs.Host
+	Notes        []string     `json:"notes,omitempty"`
+	MixedContent []string     `json:"mixedContent,omitempty"`
+	Direct       *directProbe `json:"direct,omitempty"`
+	ContentHash  string       `json:"contentHash"`
+}
+
+// newResultDocument builds the JSON document of a host, its notes and its mixed content
+func newResultDocument(host *ssllabs.Host, notes, mixedContent []string) *ResultDocument {
+	result := &ResultDocument{Host: canonicalHost(host), Notes: notes, MixedContent: mixedContent}
+	result.ContentHash = contentHash(result)
+	return result
+}
+
+// withDirect attaches the outcome of a direct probe to the document, updating its hash
+func (d *ResultDocument) withDirect(direct *directProbe) *ResultDocument {
+	if direct != nil {
+		d.Direct = direct
+		d.ContentHash = contentHash(d)
+	}
+	return d
+}
+
+// UnmarshalJSON parses a result document, the host fields keeping their raw JSON
+func (d *ResultDocument) UnmarshalJSON(data []byte) error {
+	var host ssllabs.Host
+	if err := json.Unmarshal(data, &host); err != nil {
+		return err
+	}
+	var extra struct {
+		Notes        []string     `json:"notes"`
+		MixedContent []string     `json:"mixedContent"`
+		Direct       *directProbe `json:"direct"`
+		ContentHash  string       `json:"contentHash"`
+	}
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return err
+	}
+	d.Host, d.Notes, d.MixedContent, d.Direct, d.ContentHash = &host, extra.Notes, extra.MixedContent, extra.Direct, extra.ContentHash
+	return nil
+}
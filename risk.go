@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+// Weights applied to each risk component when computing a risk score
+const (
+	gradeRiskWeight         = 40
+	expiryRiskWeight        = 20
+	vulnerabilityRiskWeight = 20
+	complianceRiskWeight    = 20
+)
+
+// gradeRisk maps an SSL Labs grade to a risk value between 0 and 100
+func gradeRisk(grade string) int {
+	switch grade {
+	case "A+":
+		return 0
+	case "A":
+		return 5
+	case "A-":
+		return 10
+	case "B":
+		return 30
+	case "C":
+		return 50
+	case "D":
+		return 65
+	case "E":
+		return 75
+	case "T", "M":
+		return 80
+	default:
+		// F and missing grades are treated as the highest risk
+		return 100
+	}
+}
+
+// expiryRisk maps the time left before certificate expiry to a risk value between 0 and 100
+func expiryRisk(cert ssllabs.Cert, now time.Time) int {
+	if cert.NotAfter == 0 {
+		return 0
+	}
+	notAfter := certNotAfter(cert)
+	switch {
+	case expired(notAfter, now):
+		return 100
+	case expiresWithin(notAfter, now, days(7)):
+		return 90
+	case expiresWithin(notAfter, now, days(30)):
+		return 60
+	case expiresWithin(notAfter, now, days(60)):
+		return 30
+	default:
+		return 0
+	}
+}
+
+// vulnerabilityRisk maps the known vulnerabilities of an endpoint to a risk value between 0 and 100
+func vulnerabilityRisk(details ssllabs.EndpointDetails) int {
+	// Vulnerabilities that allow direct compromise are the highest risk
+	if details.Heartbleed || details.DrownVulnerable || details.OpenSslCcs == 3 || details.PoodleTls == 2 {
+		return 100
+	}
+	risk := 0
+	for _, vulnerable := range []bool{details.VulnBeast, details.Poodle, details.Freak, details.Logjam} {
+		if vulnerable {
+			risk += 25
+		}
+	}
+	return risk
+}
+
+// complianceGaps lists where an endpoint falls short of the TLS baseline shared by PCI DSS
+// and NIST SP 800-52: nothing older than TLS 1.2, forward secrecy, no RC4 and a valid chain
+func complianceGaps(endpoint ssllabs.Endpoint) []string {
+	details := endpoint.Details
+	var gaps []string
+	for _, protocol := range details.Protocols {
+		if protocol.Name == "SSL" || (protocol.Name == "TLS" && (protocol.Version == "1.0" || protocol.Version == "1.1")) {
+			gaps = append(gaps, fmt.Sprintf("%s %s enabled", protocol.Name, protocol.Version))
+		}
+	}
+	// Details are only complete once the endpoint has been graded
+	if details.ForwardSecrecy == 0 && endpoint.Grade != "" {
+		gaps = append(gaps, "no forward secrecy")
+	}
+	if details.SupportsRc4 {
+		gaps = append(gaps, "RC4 supported")
+	}
+	if details.Cert.Issues != 0 || details.Chain.Issues != 0 {
+		gaps = append(gaps, "certificate or chain issues")
+	}
+	return gaps
+}
+
+// complianceRisk maps the compliance gaps of an endpoint to a risk value between 0 and 100
+func complianceRisk(endpoint ssllabs.Endpoint) int {
+	return min(25*len(complianceGaps(endpoint)), 100)
+}
+
+// riskScore computes a weighted risk score between 0 and 100 for an endpoint
+func riskScore(endpoint ssllabs.Endpoint, now time.Time) int {
+	score := gradeRisk(endpoint.Grade)*gradeRiskWeight +
+		expiryRisk(endpoint.Details.Cert, now)*expiryRiskWeight +
+		vulnerabilityRisk(endpoint.Details)*vulnerabilityRiskWeight +
+		complianceRisk(endpoint)*complianceRiskWeight
+	return score / (gradeRiskWeight + expiryRiskWeight + vulnerabilityRiskWeight + complianceRiskWeight)
+}
+
+// domainRiskScore returns the risk score of the riskiest endpoint of a host
+func domainRiskScore(host *ssllabs.Host, now time.Time) int {
+	score := 0
+	for _, endpoint := range host.Endpoints {
+		if s := riskScore(endpoint, now); s > score {
+			score = s
+		}
+	}
+	return score
+}
+
+// orgRiskScore returns the average domain risk score of a set of results, the single
+// number tracked over time for the whole organization
+func orgRiskScore(hosts []*ssllabs.Host, now time.Time) int {
+	if len(hosts) == 0 {
+		return 0
+	}
+	total := 0
+	for _, host := range hosts {
+		total += domainRiskScore(host, now)
+	}
+	return (total + len(hosts)/2) / len(hosts)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SDuque28/ssl-checker-go/ssllabs"
+)
+
+// riskEndpoint returns a graded endpoint with a modern configuration and a certificate
+// expiring after the given time
+func riskEndpoint(grade string, now time.Time, expiresIn time.Duration) ssllabs.Endpoint {
+	return ssllabs.Endpoint{IpAddress: "192.0.2.1", Grade: grade, Details: ssllabs.EndpointDetails{
+		Cert:           ssllabs.Cert{NotAfter: now.Add(expiresIn).UnixMilli()},
+		Protocols:      []ssllabs.Protocol{{ID: 771, Name: "TLS", Version: "1.2"}, {ID: 772, Name: "TLS", Version: "1.3"}},
+		ForwardSecrecy: 4,
+	}}
+}
+
+func TestExpiryRisk(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expiresIn time.Duration
+		want      int
+	}{
+		{-time.Hour, 100},
+		{days(3), 90},
+		{days(20), 60},
+		{days(45), 30},
+		{days(90), 0},
+	}
+	for _, tt := range tests {
+		cert := ssllabs.Cert{NotAfter: now.Add(tt.expiresIn).UnixMilli()}
+		if got := expiryRisk(cert, now); got != tt.want {
+			t.Errorf("expires in %s: expiryRisk = %d, want %d", tt.expiresIn, got, tt.want)
+		}
+	}
+	if got := expiryRisk(ssllabs.Cert{}, now); got != 0 {
+		t.Errorf("no certificate: expiryRisk = %d, want 0", got)
+	}
+}
+
+func TestVulnerabilityRisk(t *testing.T) {
+	tests := []struct {
+		name    string
+		details ssllabs.EndpointDetails
+		want    int
+	}{
+		{"none", ssllabs.EndpointDetails{}, 0},
+		{"Heartbleed", ssllabs.EndpointDetails{Heartbleed: true}, 100},
+		{"POODLE TLS", ssllabs.EndpointDetails{PoodleTls: 2}, 100},
+		{"BEAST and Logjam", ssllabs.EndpointDetails{VulnBeast: true, Logjam: true}, 50},
+	}
+	for _, tt := range tests {
+		if got := vulnerabilityRisk(tt.details); got != tt.want {
+			t.Errorf("%s: vulnerabilityRisk = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRiskScore(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	legacy := riskEndpoint("B", now, days(365))
+	legacy.Details.Protocols = append(legacy.Details.Protocols, ssllabs.Protocol{ID: 769, Name: "TLS", Version: "1.0"})
+	heartbleed := riskEndpoint("F", now, days(3))
+	heartbleed.Details.Heartbleed = true
+	tests := []struct {
+		name     string
+		endpoint ssllabs.Endpoint
+		want     int
+	}{
+		// Weighted average of grade (40), expiry (20), vulnerability (20) and compliance (20)
+		{"A+ modern", riskEndpoint("A+", now, days(365)), 0},
+		{"A expiring soon", riskEndpoint("A", now, days(20)), (5*40 + 60*20) / 100},
+		{"B with TLS 1.0", legacy, (30*40 + 25*20) / 100},
+		{"F with Heartbleed", heartbleed, (100*40 + 90*20 + 100*20) / 100},
+	}
+	for _, tt := range tests {
+		if got := riskScore(tt.endpoint, now); got != tt.want {
+			t.Errorf("%s: riskScore = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDomainAndOrgRiskScore(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	mixed := &ssllabs.Host{Host: "mixed.example.com", Endpoints: []ssllabs.Endpoint{
+		riskEndpoint("A+", now, days(365)),
+		riskEndpoint("C", now, days(365)),
+	}}
+	clean := &ssllabs.Host{Host: "clean.example.com", Endpoints: []ssllabs.Endpoint{riskEndpoint("A", now, days(365))}}
+	// A domain scores as its riskiest endpoint
+	if got := domainRiskScore(mixed, now); got != 20 {
+		t.Errorf("domainRiskScore = %d, want 20", got)
+	}
+	// The organization scores as the rounded average of its domains: (20 + 2) / 2
+	if got := orgRiskScore([]*ssllabs.Host{mixed, clean}, now); got != 11 {
+		t.Errorf("orgRiskScore = %d, want 11", got)
+	}
+	if got := orgRiskScore(nil, now); got != 0 {
+		t.Errorf("orgRiskScore of no domains = %d, want 0", got)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Structs to describe a port probed for a kind of service
+type servicePort struct {
+	port      int
+	role      string
+	negotiate func(conn net.Conn) error
+	// alpn is the application protocol the service must negotiate, if any
+	alpn string
+	// upgrade checks the application protocol over the established TLS connection
+	upgrade func(conn net.Conn, target probeTarget) error
+}
+
+// probeServices lists the ports probed for each service name accepted by the probe subcommand
+var probeServices = map[string][]servicePort{
+	"tls":      {{443, "HTTPS", nil, "", nil}},
+	"ldaps":    {{636, "LDAPS", nil, "", nil}, {3269, "Global Catalog", nil, "", nil}},
+	"postgres": {{5432, "PostgreSQL", negotiatePostgres, "", nil}},
+	"mysql":    {{3306, "MySQL", negotiateMySQL, "", nil}},
+	"mongodb":  {{27017, "MongoDB", nil, "", nil}},
+	"redis":    {{6379, "Redis", nil, "", nil}},
+	"mqtt":     {{8883, "MQTT", nil, "", nil}},
+	"amqp":     {{5671, "AMQP", nil, "", nil}},
+	"rdp":      {{3389, "RDP", negotiateRDP, "", nil}},
+	"winrm":    {{5986, "WinRM", nil, "", nil}},
+	"sip":      {{5061, "SIP-TLS", nil, "", nil}},
+	// gRPC runs over HTTP/2 and clients refuse servers that do not negotiate h2 with ALPN
+	"grpc": {{443, "gRPC", nil, "h2", nil}},
+	// WebSocket endpoints must complete the opening handshake, no ALPN is offered so the
+	// server stays on HTTP/1.1
+	"websocket": {{443, "WebSocket", nil, "", upgradeWebSocket}},
+}
+
+// target returns the probe target of a host on the given port of the service
+func (s servicePort) target(host string, port int) probeTarget {
+	return probeTarget{Host: host, Port: port, Role: s.role, Negotiate: s.negotiate, ALPN: s.alpn, Upgrade: s.upgrade}
+}
+
+// serviceNames returns the sorted names of the services the probe subcommand supports
+func serviceNames() []string {
+	var names []string
+	for name := range probeServices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// serviceTargets expands the entries of a host list into probe targets. A bare host is
+// probed on every port of the service, a host:port entry only on that port. WebSocket
+// services also accept wss:// URLs.
+func serviceTargets(entries []string, service string) ([]probeTarget, error) {
+	ports := probeServices[service]
+	var targets []probeTarget
+	for _, entry := range entries {
+		if strings.Contains(entry, "://") {
+			if service != "websocket" {
+				return nil, fmt.Errorf("URL %q is only supported with -service websocket", entry)
+			}
+			target, err := webSocketTarget(entry, ports[0])
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, target)
+			continue
+		}
+		host, portText, err := net.SplitHostPort(entry)
+		if err != nil {
+			// No port given, probe every port of the service
+			for _, port := range ports {
+				targets = append(targets, port.target(strings.Trim(entry, "[]"), port.port))
+			}
+			continue
+		}
+		port, err := strconv.Atoi(portText)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port in %q", entry)
+		}
+		targets = append(targets, ports[0].target(host, port))
+	}
+	return targets, nil
+}
+
+// runProbe implements the probe subcommand: it connects directly to every host of a list
+// on the ports of the selected service and reports their certificates and protocol
+// settings. It returns the process exit code.
+func runProbe(args []string) int {
+	fs := flag.NewFlagSet("probe", flag.ExitOnError)
+	service := fs.String("service", "tls", "Kind of service to probe: "+strings.Join(serviceNames(), ", "))
+	caFile := fs.String("ca-file", "", "PEM bundle of CA certificates to verify against instead of the system roots")
+	timeout := fs.Duration("timeout", 5*time.Second, "Timeout for each connection")
+	expiryDays := fs.Int("expiry-days", int(defaultProbeExpiryWarning/expiryDay), "Warn about certificates expiring within this many days")
+	resultsFile := fs.String("results-file", "", "Write the results to this file instead of stdout")
+	logFile := fs.String("log-file", "", "Append progress and status messages to this file instead of stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ssl-checker probe [-service name] [flags] hosts.txt")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	if _, ok := probeServices[*service]; !ok {
+		logf("Error: unknown service %q: expected one of %s\n", *service, strings.Join(serviceNames(), ", "))
+		return 1
+	}
+	// Redirect the logs if requested
+	closeLog, err := redirectLogs(*logFile)
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	defer closeLog()
+	entries, err := readDomainList(fs.Arg(0))
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	targets, err := serviceTargets(entries, *service)
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	p := &prober{timeout: *timeout, expiryWarning: days(*expiryDays)}
+	if *caFile != "" {
+		if p.roots, err = loadCAFile(*caFile); err != nil {
+			logf("Error: %v\n", err)
+			return 1
+		}
+	}
+	now := time.Now()
+	failed := false
+	err = writeResultsWith(*resultsFile, func(w io.Writer) error {
+		for _, target := range targets {
+			result := p.probe(target)
+			critical, warnings := p.findings(result, now)
+			displayProbeResult(w, result, critical, warnings)
+			failed = failed || len(critical) > 0
+		}
+		return nil
+	})
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	if failed {
+		return exitPolicyViolation
+	}
+	return 0
+}
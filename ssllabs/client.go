@@ -0,0 +1,262 @@
+package ssllabs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DefaultBaseURL is the SSL Labs API endpoint used by NewClient
+const DefaultBaseURL = "https://api.ssllabs.com/api/v2"
+
+// ErrUnavailable is returned when the API cannot be reached or rejects a request because it
+// is overloaded, under maintenance or the client exceeded its request rate
+var ErrUnavailable = errors.New("SSL Labs API unavailable")
+
+// Consecutive unavailable replies tolerated by WaitForAssessment, and the longest delay
+// it backs off for between them
+const (
+	maxPollRetries = 5
+	maxPollBackoff = 5 * time.Minute
+)
+
+// HTTPDoer is the part of *http.Client used by Client, so requests can be served
+// by canned responses in tests or by transports composed with retries or tracing
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client struct to interact with SSL Labs API as a client
+type Client struct {
+	baseurl string
+	client  HTTPDoer
+	// PollInterval is the delay between two status checks in WaitForAssessment
+	PollInterval time.Duration
+	// Progress is called with the current status on every poll, if set.
+	// Returning an error aborts WaitForAssessment.
+	Progress func(host *Host) error
+	// RateLimiter is waited on before every request, if set
+	RateLimiter RateLimiter
+}
+
+// NewClient initializes and returns a new Client
+func NewClient() *Client {
+	return NewClientWithDoer(&http.Client{Timeout: 30 * time.Second})
+}
+
+// NewClientWithDoer initializes and returns a new Client sending its requests through doer
+func NewClientWithDoer(doer HTTPDoer) *Client {
+	return &Client{
+		baseurl:      DefaultBaseURL,
+		client:       doer,
+		PollInterval: 10 * time.Second,
+		RateLimiter:  NewTokenBucket(1, 5),
+	}
+}
+
+// get makes a GET request to the given URL bound to the context, once the rate limiter allows it.
+// Network failures such as timeouts or refused connections are reported as ErrUnavailable
+// wrapping the cause, unless the context itself is done.
+func (s *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	if s.RateLimiter != nil {
+		if err := s.RateLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		var netErr net.Error
+		if ctx.Err() == nil && errors.As(err, &netErr) {
+			return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// checkStatus returns ErrUnavailable for the 429, 503 and 529 replies the API uses for rate
+// limiting, maintenance and overload, and a plain error for any other non-200 status
+func checkStatus(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable, 529:
+		return fmt.Errorf("%w: API returned %s", ErrUnavailable, resp.Status)
+	default:
+		return fmt.Errorf("API returned non-OK status: %s", resp.Status)
+	}
+}
+
+// analyze makes a request to the /analyze endpoint and parses the Host it returns
+func (s *Client) analyze(ctx context.Context, url, action string) (*Host, error) {
+	resp, err := s.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+	// Error replies carry a JSON body too, which would parse into an empty Host
+	if err := checkStatus(resp); err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", action, err)
+	}
+	// Read and parse the response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", action, err)
+	}
+	// Unmarshal JSON into Host struct
+	var host Host
+	if err := json.Unmarshal(body, &host); err != nil {
+		return nil, fmt.Errorf("failed to parse %s response: %v", action, err)
+	}
+	return &host, nil
+}
+
+// CheckApiStatus checks the status of the SSL Labs API
+func (s *Client) CheckApiStatus(ctx context.Context) (*Info, error) {
+	// Make a GET request to the /info endpoint
+	resp, err := s.get(ctx, s.baseurl+"/info")
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach SSL Labs API: %w", err)
+	}
+	defer resp.Body.Close()
+	// Check for non-200 status codes
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	// Read and parse the response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API response: %w", err)
+	}
+	// Unmarshal JSON into Info struct
+	var info Info
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %v", err)
+	}
+	return &info, nil
+}
+
+// WaitForCapacity blocks until the API accepts a new assessment, checking again every
+// PollInterval while all assessment slots are taken, then waits for the cool-off period
+// required between new assessments. It returns early when the context is done.
+func (s *Client) WaitForCapacity(ctx context.Context) error {
+	for {
+		info, err := s.CheckApiStatus(ctx)
+		if err != nil {
+			return err
+		}
+		if info.CurrentAssessments < info.MaxAssessments {
+			return sleep(ctx, time.Duration(info.NewAssessmentCoolOff)*time.Millisecond)
+		}
+		if err := sleep(ctx, s.PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// StartAssessment initiates a new SSL/TLS assessment for the given domain
+func (s *Client) StartAssessment(ctx context.Context, domain string, publish bool) (*Host, error) {
+	url := fmt.Sprintf("%s/analyze?host=%s&all=done&startNew=on", s.baseurl, domain)
+	// Append publish parameter if needed
+	if publish {
+		url += "&publish=on"
+	}
+	// Make a GET request to start the assessment
+	return s.analyze(ctx, url, "start assessment")
+}
+
+// CachedAssessment returns a cached assessment for the given domain if one is newer than
+// maxAge, otherwise a new assessment is started, after the cool-off period the API
+// requires between new assessments, and its initial status is returned
+func (s *Client) CachedAssessment(ctx context.Context, domain string, maxAge time.Duration, publish bool) (*Host, error) {
+	// The API takes the maximum age in whole hours, round up and check the exact age below
+	hours := int((maxAge + time.Hour - 1) / time.Hour)
+	if hours < 1 {
+		hours = 1
+	}
+	url := fmt.Sprintf("%s/analyze?host=%s&all=done&fromCache=on&maxAge=%d", s.baseurl, domain, hours)
+	// Append publish parameter if needed
+	if publish {
+		url += "&publish=on"
+	}
+	// Make a GET request to fetch the cached assessment
+	host, err := s.analyze(ctx, url, "fetch cached assessment")
+	if err != nil {
+		return nil, err
+	}
+	// A cached result within the rounded hours but older than maxAge is not reused
+	if host.Status == "READY" && time.Since(time.UnixMilli(host.TestTime)) > maxAge {
+		info, err := s.CheckApiStatus(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := sleep(ctx, time.Duration(info.NewAssessmentCoolOff)*time.Millisecond); err != nil {
+			return nil, err
+		}
+		return s.StartAssessment(ctx, domain, publish)
+	}
+	return host, nil
+}
+
+// CheckAssessmentStatus checks the status of an ongoing assessment for the given domain
+func (s *Client) CheckAssessmentStatus(ctx context.Context, domain string) (*Host, error) {
+	url := fmt.Sprintf("%s/analyze?host=%s&all=done", s.baseurl, domain)
+	// Make a GET request to check the assessment status
+	return s.analyze(ctx, url, "check assessment status")
+}
+
+// WaitForAssessment polls the assessment status until it is complete. While the API is
+// unavailable it backs off, doubling the poll interval, and gives up with ErrUnavailable
+// after maxPollRetries consecutive failures.
+func (s *Client) WaitForAssessment(ctx context.Context, domain string) (*Host, error) {
+	failures := 0
+	for {
+		// Check the current assessment status
+		host, err := s.CheckAssessmentStatus(ctx, domain)
+		if errors.Is(err, ErrUnavailable) && failures < maxPollRetries {
+			failures++
+			if err := sleep(ctx, min(s.PollInterval<<failures, maxPollBackoff)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		failures = 0
+		// Report progress to the caller
+		if s.Progress != nil {
+			if err := s.Progress(host); err != nil {
+				return nil, err
+			}
+		}
+		// If the status is READY or ERROR, return the host
+		if host.Status == "READY" || host.Status == "ERROR" {
+			return host, nil
+		}
+		if err := sleep(ctx, s.PollInterval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleep waits for the given duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,216 @@
+package ssllabs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// doerFunc adapts a function to the HTTPDoer interface
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// reply builds a canned API response
+func reply(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+// canned describes an API reply served by sequence
+type canned struct {
+	status int
+	body   string
+}
+
+// sequence returns a doer serving the given replies in order, repeating the last one, and
+// recording the URL of every request
+func sequence(requests *[]string, replies ...canned) HTTPDoer {
+	return doerFunc(func(req *http.Request) (*http.Response, error) {
+		*requests = append(*requests, req.URL.String())
+		next := replies[0]
+		if len(replies) > 1 {
+			replies = replies[1:]
+		}
+		return reply(next.status, next.body), nil
+	})
+}
+
+// testClient returns a client sending its requests to doer without rate limiting and
+// with a short poll interval
+func testClient(doer HTTPDoer) *Client {
+	client := NewClientWithDoer(doer)
+	client.RateLimiter = nil
+	client.PollInterval = time.Millisecond
+	return client
+}
+
+func TestNetworkErrorsAreUnavailable(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	client := testClient(doerFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, refused
+	}))
+	_, err := client.CheckApiStatus(context.Background())
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("CheckApiStatus = %v, want %v", err, ErrUnavailable)
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Errorf("CheckApiStatus = %v, want the network error wrapped", err)
+	}
+	_, err = client.CheckAssessmentStatus(context.Background(), "example.com")
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("CheckAssessmentStatus = %v, want %v", err, ErrUnavailable)
+	}
+}
+
+func TestCancelledContextIsNotUnavailable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := testClient(doerFunc(func(req *http.Request) (*http.Response, error) {
+		cancel()
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: req.Context().Err()}
+	}))
+	_, err := client.CheckApiStatus(ctx)
+	if errors.Is(err, ErrUnavailable) {
+		t.Errorf("CheckApiStatus = %v, want an error other than %v", err, ErrUnavailable)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("CheckApiStatus = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWaitForAssessmentRetriesUnavailable(t *testing.T) {
+	var requests []string
+	client := testClient(sequence(&requests,
+		canned{http.StatusOK, `{"host":"example.com","status":"IN_PROGRESS"}`},
+		canned{http.StatusServiceUnavailable, `{}`},
+		canned{529, `{}`},
+		canned{http.StatusOK, `{"host":"example.com","status":"READY"}`},
+	))
+	host, err := client.WaitForAssessment(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("WaitForAssessment = %v, want the READY host", err)
+	}
+	if host.Status != "READY" || len(requests) != 4 {
+		t.Errorf("status %s after %d requests, want READY after 4", host.Status, len(requests))
+	}
+}
+
+func TestWaitForAssessmentGivesUpWhenUnavailable(t *testing.T) {
+	var requests []string
+	client := testClient(sequence(&requests, canned{http.StatusTooManyRequests, `{}`}))
+	_, err := client.WaitForAssessment(context.Background(), "example.com")
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("WaitForAssessment = %v, want %v", err, ErrUnavailable)
+	}
+	if len(requests) != maxPollRetries+1 {
+		t.Errorf("%d requests, want %d", len(requests), maxPollRetries+1)
+	}
+}
+
+func TestCheckStatus(t *testing.T) {
+	tests := []struct {
+		status          int
+		wantErr         bool
+		wantUnavailable bool
+	}{
+		{http.StatusOK, false, false},
+		{http.StatusTooManyRequests, true, true},
+		{http.StatusServiceUnavailable, true, true},
+		{529, true, true},
+		{http.StatusBadRequest, true, false},
+		{http.StatusInternalServerError, true, false},
+	}
+	for _, tt := range tests {
+		err := checkStatus(reply(tt.status, ""))
+		if (err != nil) != tt.wantErr || errors.Is(err, ErrUnavailable) != tt.wantUnavailable {
+			t.Errorf("checkStatus(%d) = %v, want error %t, unavailable %t", tt.status, err, tt.wantErr, tt.wantUnavailable)
+		}
+	}
+}
+
+func TestErrorRepliesAreNotParsed(t *testing.T) {
+	var requests []string
+	client := testClient(sequence(&requests, canned{http.StatusBadRequest, `{"errors":[{"message":"invalid host"}]}`}))
+	if host, err := client.CheckAssessmentStatus(context.Background(), "example.com"); err == nil {
+		t.Errorf("CheckAssessmentStatus = %+v, want an error", host)
+	}
+}
+
+func TestCachedAssessmentMaxAge(t *testing.T) {
+	tests := []struct {
+		name      string
+		age       time.Duration
+		maxAge    time.Duration
+		wantHours string
+		wantNew   bool
+	}{
+		{"fresh result", 10 * time.Minute, 30 * time.Minute, "maxAge=1", false},
+		{"stale within the rounded hour", 45 * time.Minute, 30 * time.Minute, "maxAge=1", true},
+		{"fresh result over several hours", 90 * time.Minute, 2 * time.Hour, "maxAge=2", false},
+	}
+	for _, tt := range tests {
+		testTime := time.Now().Add(-tt.age).UnixMilli()
+		var requests []string
+		client := testClient(sequence(&requests,
+			canned{http.StatusOK, fmt.Sprintf(`{"host":"example.com","status":"READY","testTime":%d}`, testTime)},
+			canned{http.StatusOK, `{"maxAssessments":25,"currentAssessments":0,"newAssessmentCoolOff":1}`},
+			canned{http.StatusOK, `{"host":"example.com","status":"DNS"}`},
+		))
+		host, err := client.CachedAssessment(context.Background(), "example.com", tt.maxAge, false)
+		if err != nil {
+			t.Fatalf("%s: CachedAssessment = %v", tt.name, err)
+		}
+		if !strings.Contains(requests[0], "fromCache=on") || !strings.Contains(requests[0], tt.wantHours) {
+			t.Errorf("%s: first request %s, want fromCache=on and %s", tt.name, requests[0], tt.wantHours)
+		}
+		startedNew := strings.Contains(requests[len(requests)-1], "startNew=on")
+		if startedNew != tt.wantNew {
+			t.Errorf("%s: requests %q, want a new assessment %t", tt.name, requests, tt.wantNew)
+		}
+		// The cool-off is read from the API status before a new assessment is started
+		if tt.wantNew && (len(requests) != 3 || !strings.Contains(requests[1], "/info")) {
+			t.Errorf("%s: requests %q, want the API status checked before the new assessment", tt.name, requests)
+		}
+		if wantStatus := map[bool]string{false: "READY", true: "DNS"}[tt.wantNew]; host.Status != wantStatus {
+			t.Errorf("%s: status %s, want %s", tt.name, host.Status, wantStatus)
+		}
+	}
+}
+
+func TestWaitForCapacity(t *testing.T) {
+	var requests []string
+	client := testClient(sequence(&requests,
+		canned{http.StatusOK, `{"maxAssessments":2,"currentAssessments":2,"newAssessmentCoolOff":1}`},
+		canned{http.StatusOK, `{"maxAssessments":2,"currentAssessments":1,"newAssessmentCoolOff":1}`},
+	))
+	if err := client.WaitForCapacity(context.Background()); err != nil {
+		t.Fatalf("WaitForCapacity = %v", err)
+	}
+	if len(requests) != 2 {
+		t.Errorf("requests %q, want the status checked until a slot is free", requests)
+	}
+}
+
+func TestWaitForCapacityCancelled(t *testing.T) {
+	var requests []string
+	client := testClient(sequence(&requests, canned{http.StatusOK, `{"maxAssessments":2,"currentAssessments":2}`}))
+	client.PollInterval = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := client.WaitForCapacity(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitForCapacity = %v, want the deadline to interrupt the wait", err)
+	}
+}
@@ -0,0 +1,66 @@
+package ssllabs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter is consulted before every API request. Wait blocks until the request may be
+// sent, or returns an error if the context is done first.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// TokenBucket is a RateLimiter allowing bursts of up to burst requests, refilled at rate
+// requests per second
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket initializes and returns a new full TokenBucket. It panics if rate is not
+// a positive finite number or burst is lower than 1, since Wait could never return.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if !(rate > 0) || math.IsInf(rate, 1) {
+		panic(fmt.Sprintf("ssllabs: NewTokenBucket rate must be positive and finite, got %v", rate))
+	}
+	if burst < 1 {
+		panic(fmt.Sprintf("ssllabs: NewTokenBucket burst must be at least 1, got %d", burst))
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait takes a token from the bucket, waiting for one to be refilled if it is empty
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		// Refill the tokens accumulated since the last call
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
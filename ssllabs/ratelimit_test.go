@@ -0,0 +1,76 @@
+package ssllabs
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNewTokenBucketRejectsInvalidArguments(t *testing.T) {
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+	}{
+		{"zero rate", 0, 1},
+		{"negative rate", -1, 1},
+		{"NaN rate", math.NaN(), 1},
+		{"infinite rate", math.Inf(1), 1},
+		{"zero burst", 1, 0},
+		{"negative burst", 1, -1},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: NewTokenBucket(%v, %d) did not panic", tt.name, tt.rate, tt.burst)
+				}
+			}()
+			NewTokenBucket(tt.rate, tt.burst)
+		}()
+	}
+}
+
+func TestTokenBucketBurst(t *testing.T) {
+	bucket := NewTokenBucket(1, 3)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := bucket.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("burst of 3 took %s, want no wait", elapsed)
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	bucket := NewTokenBucket(20, 1)
+	ctx := context.Background()
+	if err := bucket.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// The second token is refilled after 1/20s
+	start := time.Now()
+	if err := bucket.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond || elapsed > time.Second {
+		t.Errorf("second token took %s, want about 50ms", elapsed)
+	}
+}
+
+func TestTokenBucketWaitHonoursContext(t *testing.T) {
+	bucket := NewTokenBucket(0.001, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bucket.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := bucket.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait on an empty bucket = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
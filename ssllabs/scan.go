@@ -0,0 +1,68 @@
+package ssllabs
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMaxAssessments is returned by Scan when the API does not accept new assessments
+var ErrMaxAssessments = errors.New("maximum number of concurrent assessments reached")
+
+// Options to control a Scan
+type Options struct {
+	// Client used for the scan; a new Client is created if nil
+	Client *Client
+	// Publish the results on the SSL Labs board
+	Publish bool
+	// FromCache returns a cached assessment when available instead of starting a new one
+	FromCache bool
+	// MaxAge is the maximum age of a cached assessment used with FromCache
+	MaxAge time.Duration
+}
+
+// Result of a completed Scan
+type Result struct {
+	Info *Info
+	Host *Host
+}
+
+// Scan checks the API status, honours the new assessment cool-off, starts an assessment
+// for the given host and waits for it to complete. A host whose assessment failed is
+// returned with an ERROR status rather than as an error.
+func Scan(ctx context.Context, domain string, opts Options) (*Result, error) {
+	client := opts.Client
+	if client == nil {
+		client = NewClient()
+	}
+	// Check API status and capacity
+	info, err := client.CheckApiStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info.CurrentAssessments >= info.MaxAssessments {
+		return nil, ErrMaxAssessments
+	}
+	// Start the assessment, or look up a cached one
+	var host *Host
+	if opts.FromCache {
+		host, err = client.CachedAssessment(ctx, domain, opts.MaxAge, opts.Publish)
+	} else {
+		// Wait for the cool-off period required between new assessments
+		if err := sleep(ctx, time.Duration(info.NewAssessmentCoolOff)*time.Millisecond); err != nil {
+			return nil, err
+		}
+		host, err = client.StartAssessment(ctx, domain, opts.Publish)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Wait for the assessment to complete
+	if host.Status != "READY" && host.Status != "ERROR" {
+		host, err = client.WaitForAssessment(ctx, domain)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Result{Info: info, Host: host}, nil
+}
@@ -0,0 +1,63 @@
+package ssllabs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	var requests []string
+	client := testClient(sequence(&requests,
+		canned{http.StatusOK, `{"criteriaVersion":"2009q","maxAssessments":25,"currentAssessments":0,"newAssessmentCoolOff":1}`},
+		canned{http.StatusOK, `{"host":"example.com","status":"DNS"}`},
+		canned{http.StatusOK, `{"host":"example.com","status":"IN_PROGRESS"}`},
+		canned{http.StatusOK, `{"host":"example.com","status":"READY","endpoints":[{"ipAddress":"192.0.2.1","grade":"A"}]}`},
+	))
+	result, err := Scan(context.Background(), "example.com", Options{Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Info.CriteriaVersion != "2009q" || result.Host.Status != "READY" || result.Host.Endpoints[0].Grade != "A" {
+		t.Errorf("result = %+v, %+v, want the READY host graded A", result.Info, result.Host)
+	}
+	if len(requests) != 4 || !strings.HasSuffix(requests[0], "/info") || !strings.Contains(requests[1], "startNew=on") {
+		t.Errorf("requests = %q, want info, a new assessment and two polls", requests)
+	}
+}
+
+func TestScanMaxAssessments(t *testing.T) {
+	var requests []string
+	client := testClient(sequence(&requests, canned{http.StatusOK, `{"maxAssessments":2,"currentAssessments":2}`}))
+	if _, err := Scan(context.Background(), "example.com", Options{Client: client}); !errors.Is(err, ErrMaxAssessments) {
+		t.Errorf("Scan = %v, want %v", err, ErrMaxAssessments)
+	}
+	if len(requests) != 1 {
+		t.Errorf("requests = %q, want only the info request", requests)
+	}
+}
+
+func TestScanReturnsFailedAssessments(t *testing.T) {
+	var requests []string
+	client := testClient(sequence(&requests,
+		canned{http.StatusOK, `{"maxAssessments":25}`},
+		canned{http.StatusOK, `{"host":"example.com","status":"ERROR","statusMessage":"Unable to resolve domain name"}`},
+	))
+	result, err := Scan(context.Background(), "example.com", Options{Client: client})
+	if err != nil {
+		t.Fatalf("Scan = %v, want the failed host", err)
+	}
+	if result.Host.Status != "ERROR" || result.Host.StatusMessage != "Unable to resolve domain name" {
+		t.Errorf("host = %+v, want the ERROR status", result.Host)
+	}
+}
+
+func TestScanUnavailable(t *testing.T) {
+	var requests []string
+	client := testClient(sequence(&requests, canned{http.StatusServiceUnavailable, `{}`}))
+	if _, err := Scan(context.Background(), "example.com", Options{Client: client}); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Scan = %v, want %v", err, ErrUnavailable)
+	}
+}
@@ -0,0 +1,179 @@
+package ssllabs
+
+import "encoding/json"
+
+// Structs to parse Info JSON responses from SSL Labs API
+type Info struct {
+	Version              string   `json:"version"`
+	CriteriaVersion      string   `json:"criteriaVersion"`
+	MaxAssessments       int      `json:"maxAssessments"`
+	CurrentAssessments   int      `json:"currentAssessments"`
+	NewAssessmentCoolOff int64    `json:"newAssessmentCoolOff"`
+	Messages             []string `json:"messages"`
+}
+
+// Structs to parse Host JSON responses from SSL Labs API
+type Host struct {
+	Host            string     `json:"host"`
+	Port            int        `json:"port"`
+	Protocol        string     `json:"protocol"`
+	IsPublic        bool       `json:"isPublic"`
+	Status          string     `json:"status"`
+	StatusMessage   string     `json:"statusMessage"`
+	StartTime       int64      `json:"startTime"`
+	TestTime        int64      `json:"testTime"`
+	EngineVersion   string     `json:"engineVersion"`
+	CriteriaVersion string     `json:"criteriaVersion"`
+	Endpoints       []Endpoint `json:"endpoints"`
+	// Raw holds the unmodified API response, including fields not modeled here
+	Raw json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON parses a Host and keeps a copy of the raw JSON
+func (h *Host) UnmarshalJSON(data []byte) error {
+	type host Host
+	if err := json.Unmarshal(data, (*host)(h)); err != nil {
+		return err
+	}
+	h.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// RawField returns the raw JSON of a top-level host field, modeled or not
+func (h *Host) RawField(name string) (json.RawMessage, bool) {
+	return rawField(h.Raw, name)
+}
+
+// Structs to parse Endpoint JSON responses from SSL Labs API
+type Endpoint struct {
+	IpAddress         string          `json:"ipAddress"`
+	ServerName        string          `json:"serverName"`
+	StatusMessage     string          `json:"statusMessage"`
+	StatusDetails     string          `json:"statusDetails"`
+	Grade             string          `json:"grade"`
+	GradeTrustIgnored string          `json:"gradeTrustIgnored"`
+	HasWarnings       bool            `json:"hasWarnings"`
+	Progress          int             `json:"progress"`
+	Duration          int             `json:"duration"`
+	Eta               int             `json:"eta"`
+	Details           EndpointDetails `json:"details"`
+	// Raw holds the unmodified endpoint JSON, including fields not modeled here
+	Raw json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON parses an Endpoint and keeps a copy of the raw JSON
+func (e *Endpoint) UnmarshalJSON(data []byte) error {
+	type endpoint Endpoint
+	if err := json.Unmarshal(data, (*endpoint)(e)); err != nil {
+		return err
+	}
+	e.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// RawField returns the raw JSON of a top-level endpoint field, modeled or not
+func (e *Endpoint) RawField(name string) (json.RawMessage, bool) {
+	return rawField(e.Raw, name)
+}
+
+// rawField looks up a top-level field in a raw JSON object
+func rawField(raw json.RawMessage, name string) (json.RawMessage, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, false
+	}
+	value, ok := fields[name]
+	return value, ok
+}
+
+// Structs to parse EndpointDetails JSON responses from SSL Labs API
+type EndpointDetails struct {
+	Cert            Cert       `json:"cert"`
+	Chain           Chain      `json:"chain"`
+	Protocols       []Protocol `json:"protocols"`
+	VulnBeast       bool       `json:"vulnBeast"`
+	Heartbleed      bool       `json:"heartbleed"`
+	Poodle          bool       `json:"poodle"`
+	PoodleTls       int        `json:"poodleTls"`
+	OpenSslCcs      int        `json:"openSslCcs"`
+	Freak           bool       `json:"freak"`
+	Logjam          bool       `json:"logjam"`
+	DrownVulnerable bool       `json:"drownVulnerable"`
+	SupportsRc4     bool       `json:"supportsRc4"`
+	Rc4WithModern   bool       `json:"rc4WithModern"`
+	ForwardSecrecy  int        `json:"forwardSecrecy"`
+	RenegSupport    int        `json:"renegSupport"`
+}
+
+// Structs to parse Cert JSON responses from SSL Labs API
+type Cert struct {
+	Subject   string `json:"subject"`
+	NotBefore int64  `json:"notBefore"`
+	NotAfter  int64  `json:"notAfter"`
+	Issues    int    `json:"issues"`
+	Sha1Hash  string `json:"sha1Hash"`
+}
+
+// Structs to parse Protocol JSON responses from SSL Labs API
+type Protocol struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// certIssues describes each bit of Cert.Issues, from the least significant bit
+var certIssues = []string{
+	"no chain of trust",
+	"certificate not yet valid",
+	"certificate expired",
+	"hostname mismatch",
+	"certificate revoked",
+	"bad common name",
+	"self-signed certificate",
+	"blacklisted certificate",
+	"insecure signature",
+}
+
+// IssueDescriptions returns a description of every issue flagged on the certificate
+func (c Cert) IssueDescriptions() []string {
+	return issueDescriptions(c.Issues, certIssues)
+}
+
+// Structs to parse Chain JSON responses from SSL Labs API
+type Chain struct {
+	Certs  []ChainCert `json:"certs"`
+	Issues int         `json:"issues"`
+}
+
+// chainIssues describes each bit of Chain.Issues, from the least significant bit
+var chainIssues = []string{
+	"",
+	"incomplete chain",
+	"chain contains unrelated or duplicate certificates",
+	"incorrect certificate order",
+	"chain contains a self-signed root certificate",
+	"chain could not be validated",
+}
+
+// IssueDescriptions returns a description of every issue flagged on the chain
+func (c Chain) IssueDescriptions() []string {
+	return issueDescriptions(c.Issues, chainIssues)
+}
+
+// issueDescriptions maps the bits set in an issues bitmask to their descriptions
+func issueDescriptions(issues int, descriptions []string) []string {
+	var result []string
+	for bit, description := range descriptions {
+		if issues&(1<<bit) != 0 && description != "" {
+			result = append(result, description)
+		}
+	}
+	return result
+}
+
+// Structs to parse ChainCert JSON responses from SSL Labs API
+type ChainCert struct {
+	Subject string `json:"subject"`
+	Label   string `json:"label"`
+	Raw     string `json:"raw"`
+}
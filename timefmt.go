@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Supported formats for timestamps in reports
+const (
+	timeFormatDefault  = "default"
+	timeFormatRFC3339  = "rfc3339"
+	timeFormatUnix     = "unix"
+	timeFormatRelative = "relative"
+)
+
+// timeLocation is the time zone used for timestamps in reports
+var timeLocation = time.Local
+
+// timeFormat is the format used for timestamps in reports
+var timeFormat = timeFormatDefault
+
+// setTimeZone sets the time zone used for timestamps in reports from an IANA name
+func setTimeZone(name string) error {
+	if name == "" {
+		return nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid time zone %q: %v", name, err)
+	}
+	timeLocation = location
+	return nil
+}
+
+// setTimeFormat sets the format used for timestamps in reports
+func setTimeFormat(format string) error {
+	switch format {
+	case timeFormatDefault, timeFormatRFC3339, timeFormatUnix, timeFormatRelative:
+		timeFormat = format
+		return nil
+	default:
+		return fmt.Errorf("invalid time format %q: expected %s, %s, %s or %s", format, timeFormatDefault, timeFormatRFC3339, timeFormatUnix, timeFormatRelative)
+	}
+}
+
+// formatTime formats a timestamp for reports in the configured time zone and format
+func formatTime(t time.Time) string {
+	switch timeFormat {
+	case timeFormatRFC3339:
+		return t.In(timeLocation).Format(time.RFC3339)
+	case timeFormatUnix:
+		return fmt.Sprintf("%d", t.Unix())
+	case timeFormatRelative:
+		return relativeTime(t, time.Now())
+	default:
+		return t.In(timeLocation).Format("2006-01-02 15:04:05 MST")
+	}
+}
+
+// relativeTime describes t relative to now, e.g. "3 days ago" or "in 2 hours"
+func relativeTime(t, now time.Time) string {
+	d := t.Sub(now)
+	future := d > 0
+	if !future {
+		d = -d
+	}
+	var amount string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		amount = plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		amount = plural(int(d/time.Hour), "hour")
+	default:
+		amount = plural(int(d/(24*time.Hour)), "day")
+	}
+	if future {
+		return "in " + amount
+	}
+	return amount + " ago"
+}
+
+// plural formats a count with a singular or plural unit
+func plural(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
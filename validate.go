@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// normalizeTarget lowercases a host name and drops its trailing dot, so that duplicates
+// written differently are recognized
+func normalizeTarget(entry string) string {
+	return strings.TrimSuffix(strings.ToLower(entry), ".")
+}
+
+// validateHostname checks that a target is a fully qualified host name SSL Labs accepts
+func validateHostname(name string) error {
+	if net.ParseIP(name) != nil {
+		return fmt.Errorf("IP addresses are not accepted, use a host name")
+	}
+	if len(name) > 253 {
+		return fmt.Errorf("host name longer than 253 characters")
+	}
+	labels := strings.Split(name, ".")
+	if len(labels) < 2 {
+		return fmt.Errorf("not a fully qualified host name")
+	}
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 {
+			return fmt.Errorf("invalid label length in %q", label)
+		}
+		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return fmt.Errorf("label %q starts or ends with a hyphen", label)
+		}
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return fmt.Errorf("invalid character %q", c)
+			}
+		}
+	}
+	return nil
+}
+
+// isPublicIP reports whether an address is reachable from the internet, and so by SSL Labs
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsUnspecified() &&
+		!ip.IsMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsLinkLocalMulticast()
+}
+
+// checkPublicResolution resolves a host name and checks it has at least one public address.
+// Non-public addresses next to public ones are returned as warnings.
+func checkPublicResolution(ctx context.Context, name string, timeout time.Duration) ([]string, error) {
+	lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupIP(lookupCtx, "ip", name)
+	if err != nil {
+		return nil, fmt.Errorf("DNS lookup failed: %v", err)
+	}
+	var public, private []string
+	for _, ip := range ips {
+		if isPublicIP(ip) {
+			public = append(public, ip.String())
+		} else {
+			private = append(private, ip.String())
+		}
+	}
+	if len(public) == 0 {
+		return nil, fmt.Errorf("resolves only to non-public addresses (%s)", strings.Join(private, ", "))
+	}
+	if len(private) > 0 {
+		return []string{fmt.Sprintf("also resolves to non-public addresses (%s)", strings.Join(private, ", "))}, nil
+	}
+	return nil, nil
+}
+
+// runValidateTargets implements the validate-targets subcommand: it checks every entry of
+// a host list before it is scanned and writes the valid, deduplicated entries as a cleaned
+// list, reporting the rejected ones on stderr. It returns the process exit code.
+func runValidateTargets(args []string) int {
+	fs := flag.NewFlagSet("validate-targets", flag.ExitOnError)
+	out := fs.String("o", "", "Write the cleaned list to this file instead of stdout")
+	offline := fs.Bool("offline", false, "Only check syntax and duplicates, without DNS lookups or connections")
+	timeout := fs.Duration("timeout", 5*time.Second, "Timeout for each DNS lookup and connection")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ssl-checker validate-targets [-o cleaned.txt] hosts.txt")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	entries, err := readDomainList(fs.Arg(0))
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	ctx := context.Background()
+	seen := make(map[string]bool)
+	var cleaned []string
+	for _, entry := range entries {
+		name := normalizeTarget(entry)
+		// Check the syntax and duplicates before touching the network
+		if err := validateHostname(name); err != nil {
+			logf("Rejected %s: %v\n", entry, err)
+			continue
+		}
+		if seen[name] {
+			logf("Rejected %s: duplicate of an earlier entry\n", entry)
+			continue
+		}
+		seen[name] = true
+		if !*offline {
+			warnings, err := checkPublicResolution(ctx, name, *timeout)
+			if err == nil {
+				err = checkLiveness(ctx, name, 443, *timeout)
+			}
+			if err != nil {
+				logf("Rejected %s: %v\n", entry, err)
+				continue
+			}
+			for _, warning := range warnings {
+				logf("Warning %s: %s\n", entry, warning)
+			}
+		}
+		cleaned = append(cleaned, name)
+	}
+	logf("%d of %d targets valid\n", len(cleaned), len(entries))
+	err = writeResultsWith(*out, func(w io.Writer) error {
+		for _, name := range cleaned {
+			fmt.Fprintln(w, name)
+		}
+		return nil
+	})
+	if err != nil {
+		logf("Error: %v\n", err)
+		return 1
+	}
+	if len(cleaned) < len(entries) {
+		return exitPolicyViolation
+	}
+	return 0
+}
@@ -0,0 +1,28 @@
+package main
+
+import "github.com/SDuque28/ssl-checker-go/ssllabs"
+
+// endpointWarnings lists the issues behind an endpoint's warnings, from its certificate,
+// chain and configuration details
+func endpointWarnings(endpoint ssllabs.Endpoint) []string {
+	details := endpoint.Details
+	warnings := append(details.Cert.IssueDescriptions(), details.Chain.IssueDescriptions()...)
+	if details.SupportsRc4 {
+		warnings = append(warnings, "RC4 cipher suites supported")
+	}
+	if details.Rc4WithModern {
+		warnings = append(warnings, "RC4 used with modern clients")
+	}
+	// Details are only complete once the endpoint has been graded
+	if details.ForwardSecrecy == 0 && endpoint.Grade != "" {
+		warnings = append(warnings, "no forward secrecy")
+	}
+	// Bit 0 of renegSupport flags insecure client-initiated renegotiation
+	if details.RenegSupport&1 != 0 {
+		warnings = append(warnings, "insecure client-initiated renegotiation")
+	}
+	if details.VulnBeast {
+		warnings = append(warnings, "vulnerable to BEAST")
+	}
+	return warnings
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// CloudEvents attributes of the scan completion event
+const (
+	cloudEventsSpecVersion = "1.0"
+	cloudEventSource       = "ssl-checker"
+	cloudEventScanComplete = "io.github.sduque28.ssl-checker.scan.completed"
+	cloudEventCertExpiring = "io.github.sduque28.ssl-checker.certificate.expiring"
+)
+
+// Structs to describe a CloudEvent in structured JSON mode
+type CloudEvent struct {
+	SpecVersion     string `json:"specversion"`
+	ID              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	Subject         string `json:"subject"`
+	Time            string `json:"time"`
+	DataContentType string `json:"datacontenttype"`
+	Data            any    `json:"data"`
+}
+
+// newScanCompletedEvent builds the CloudEvent announcing a completed scan, carrying the
+// same document as -output json
+func newScanCompletedEvent(result *ResultDocument, now time.Time) (CloudEvent, error) {
+	return newCloudEvent(cloudEventScanComplete, result.Host.Host, result, now)
+}
+
+// newCloudEvent builds a CloudEvent of the given type with a random ID
+func newCloudEvent(eventType, subject string, data any, now time.Time) (CloudEvent, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return CloudEvent{}, fmt.Errorf("failed to generate event ID: %v", err)
+	}
+	return CloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              hex.EncodeToString(id),
+		Source:          cloudEventSource,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            now.UTC().Format(time.RFC3339),
+		DataContentType: "application/json",
+		Data:            data,
+	}, nil
+}
+
+// sendWebhook posts a CloudEvent to the webhook URL
+func sendWebhook(url string, event CloudEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %v", err)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(url, "application/cloudevents+json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	// Check for non-2xx status codes
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned non-OK status: %s", resp.Status)
+	}
+	return nil
+}
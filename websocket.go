@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// GUID appended to the key of a WebSocket handshake to compute the accept value (RFC 6455)
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// upgradeWebSocket sends a WebSocket opening handshake over an established TLS connection
+// and checks the server switched protocols with the accept value matching the key
+func upgradeWebSocket(conn net.Conn, target probeTarget) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate handshake key: %v", err)
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	path := target.Path
+	if path == "" {
+		path = "/"
+	}
+	host := target.Host
+	if target.Port != 443 {
+		host = target.address()
+	}
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Scheme: "https", Host: host, Opaque: path},
+		Host:       host,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-WebSocket-Key":     {key},
+			"Sec-WebSocket-Version": {"13"},
+			"User-Agent":            {"ssl-checker"},
+		},
+	}
+	if err := req.Write(conn); err != nil {
+		return fmt.Errorf("failed to send upgrade request: %v", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return fmt.Errorf("failed to read upgrade response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return fmt.Errorf("server answered %s instead of 101 Switching Protocols", resp.Status)
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") {
+		return fmt.Errorf("server switched to %q instead of websocket", resp.Header.Get("Upgrade"))
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != webSocketAccept(key) {
+		return fmt.Errorf("server sent an invalid Sec-WebSocket-Accept value")
+	}
+	return nil
+}
+
+// webSocketAccept returns the Sec-WebSocket-Accept value expected for a handshake key
+func webSocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// webSocketTarget parses a wss:// URL into a probe target, on port 443 unless the URL
+// names another port
+func webSocketTarget(rawURL string, service servicePort) (probeTarget, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "wss" || u.Hostname() == "" {
+		return probeTarget{}, fmt.Errorf("invalid WebSocket URL %q: expected wss://host[:port][/path]", rawURL)
+	}
+	port := service.port
+	if u.Port() != "" {
+		if port, err = strconv.Atoi(u.Port()); err != nil || port < 1 || port > 65535 {
+			return probeTarget{}, fmt.Errorf("invalid port in %q", rawURL)
+		}
+	}
+	target := service.target(u.Hostname(), port)
+	target.Path = u.RequestURI()
+	return target, nil
+}